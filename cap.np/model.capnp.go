@@ -455,6 +455,90 @@ func (p SubDir_Promise) Struct() (SubDir, error) {
 	return SubDir{s}, err
 }
 
+type XAttr struct{ capnp.Struct }
+
+// XAttr_TypeID is the unique identifier for the type XAttr.
+const XAttr_TypeID = 0xd969a1a01af8294a
+
+func NewXAttr(s *capnp.Segment) (XAttr, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2})
+	return XAttr{st}, err
+}
+
+func NewRootXAttr(s *capnp.Segment) (XAttr, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2})
+	return XAttr{st}, err
+}
+
+func ReadRootXAttr(msg *capnp.Message) (XAttr, error) {
+	root, err := msg.RootPtr()
+	return XAttr{root.Struct()}, err
+}
+
+func (s XAttr) String() string {
+	str, _ := text.Marshal(0xd969a1a01af8294a, s.Struct)
+	return str
+}
+
+func (s XAttr) Name() (string, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.Text(), err
+}
+
+func (s XAttr) HasName() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s XAttr) NameBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.TextBytes(), err
+}
+
+func (s XAttr) SetName(v string) error {
+	return s.Struct.SetText(0, v)
+}
+
+func (s XAttr) Value() ([]byte, error) {
+	p, err := s.Struct.Ptr(1)
+	return []byte(p.Data()), err
+}
+
+func (s XAttr) HasValue() bool {
+	p, err := s.Struct.Ptr(1)
+	return p.IsValid() || err != nil
+}
+
+func (s XAttr) SetValue(v []byte) error {
+	return s.Struct.SetData(1, v)
+}
+
+// XAttr_List is a list of XAttr.
+type XAttr_List struct{ capnp.List }
+
+// NewXAttr creates a new list of XAttr.
+func NewXAttr_List(s *capnp.Segment, sz int32) (XAttr_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2}, sz)
+	return XAttr_List{l}, err
+}
+
+func (s XAttr_List) At(i int) XAttr { return XAttr{s.List.Struct(i)} }
+
+func (s XAttr_List) Set(i int, v XAttr) error { return s.List.SetStruct(i, v.Struct) }
+
+func (s XAttr_List) String() string {
+	str, _ := text.MarshalList(0xd969a1a01af8294a, s.List)
+	return str
+}
+
+// XAttr_Promise is a wrapper for a XAttr promised by a client call.
+type XAttr_Promise struct{ *capnp.Pipeline }
+
+func (p XAttr_Promise) Struct() (XAttr, error) {
+	s, err := p.Pipeline.Struct()
+	return XAttr{s}, err
+}
+
 type Inode struct{ capnp.Struct }
 type Inode_attributes Inode
 type Inode_attributes_Which uint16
@@ -486,12 +570,12 @@ func (w Inode_attributes_Which) String() string {
 const Inode_TypeID = 0xc0029f81b3eee594
 
 func NewInode(s *capnp.Segment) (Inode, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 24, PointerCount: 3})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 32, PointerCount: 4})
 	return Inode{st}, err
 }
 
 func NewRootInode(s *capnp.Segment) (Inode, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 24, PointerCount: 3})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 32, PointerCount: 4})
 	return Inode{st}, err
 }
 
@@ -704,12 +788,53 @@ func (s Inode) SetCreationTime(v uint32) {
 	s.Struct.SetUint32(16, v)
 }
 
+func (s Inode) Xattrs() (XAttr_List, error) {
+	p, err := s.Struct.Ptr(3)
+	return XAttr_List{List: p.List()}, err
+}
+
+func (s Inode) HasXattrs() bool {
+	p, err := s.Struct.Ptr(3)
+	return p.IsValid() || err != nil
+}
+
+func (s Inode) SetXattrs(v XAttr_List) error {
+	return s.Struct.SetPtr(3, v.List.ToPtr())
+}
+
+// NewXattrs sets the xattrs field to a newly
+// allocated XAttr_List, preferring placement in s's segment.
+func (s Inode) NewXattrs(n int32) (XAttr_List, error) {
+	l, err := NewXAttr_List(s.Struct.Segment(), n)
+	if err != nil {
+		return XAttr_List{}, err
+	}
+	err = s.Struct.SetPtr(3, l.List.ToPtr())
+	return l, err
+}
+
+func (s Inode) InodeId() uint64 {
+	return s.Struct.Uint64(24)
+}
+
+func (s Inode) SetInodeId(v uint64) {
+	s.Struct.SetUint64(24, v)
+}
+
+func (s Inode) Nlink() uint32 {
+	return s.Struct.Uint32(20)
+}
+
+func (s Inode) SetNlink(v uint32) {
+	s.Struct.SetUint32(20, v)
+}
+
 // Inode_List is a list of Inode.
 type Inode_List struct{ capnp.List }
 
 // NewInode creates a new list of Inode.
 func NewInode_List(s *capnp.Segment, sz int32) (Inode_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 24, PointerCount: 3}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 32, PointerCount: 4}, sz)
 	return Inode_List{l}, err
 }
 
@@ -764,12 +889,12 @@ type Dir struct{ capnp.Struct }
 const Dir_TypeID = 0x8a228653b964fd48
 
 func NewDir(s *capnp.Segment) (Dir, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 5})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 6})
 	return Dir{st}, err
 }
 
 func NewRootDir(s *capnp.Segment) (Dir, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 5})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 6})
 	return Dir{st}, err
 }
 
@@ -908,12 +1033,37 @@ func (s Dir) SetCreationTime(v uint32) {
 	s.Struct.SetUint32(12, v)
 }
 
+func (s Dir) Xattrs() (XAttr_List, error) {
+	p, err := s.Struct.Ptr(5)
+	return XAttr_List{List: p.List()}, err
+}
+
+func (s Dir) HasXattrs() bool {
+	p, err := s.Struct.Ptr(5)
+	return p.IsValid() || err != nil
+}
+
+func (s Dir) SetXattrs(v XAttr_List) error {
+	return s.Struct.SetPtr(5, v.List.ToPtr())
+}
+
+// NewXattrs sets the xattrs field to a newly
+// allocated XAttr_List, preferring placement in s's segment.
+func (s Dir) NewXattrs(n int32) (XAttr_List, error) {
+	l, err := NewXAttr_List(s.Struct.Segment(), n)
+	if err != nil {
+		return XAttr_List{}, err
+	}
+	err = s.Struct.SetPtr(5, l.List.ToPtr())
+	return l, err
+}
+
 // Dir_List is a list of Dir.
 type Dir_List struct{ capnp.List }
 
 // NewDir creates a new list of Dir.
 func NewDir_List(s *capnp.Segment, sz int32) (Dir_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 16, PointerCount: 5}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 16, PointerCount: 6}, sz)
 	return Dir_List{l}, err
 }
 
@@ -1255,89 +1405,96 @@ func (p ACI_Right_Promise) Struct() (ACI_Right, error) {
 	return ACI_Right{s}, err
 }
 
-const schema_ae9223e76351538a = "x\xda\xacV\x7f\x88TU\x14>\xdf\xbdo~,\xcc" +
-	"6\xf3z#\xfe\xc0e\xd2\x92t\xc9EW\x83\\\x8a" +
-	"\xb5M\xcb\x15\x97\xbc;J\x18\x06\xbd\x9dy\xbb\xfb\x9c" +
-	"\xd9\x99a\xe6M\xb2B,\x1aI.\x88\xac\xac\xfd\xc2" +
-	"\xc8L\xc2\xbf*\xcc \xc9\xc8\"\x08\"\xa4$\xc1\xc2" +
-	"\x8d\"m1B\x8a\x94\\p{q\xe6\xed\xbc\x99]" +
-	"\xa7\xff\x9a\xbf\xee|\xef\xdc\xf3\x9d\xfb\x9d\xef\x9d\xfbV" +
-	"-\x97\xeb\xc5\xea\xc0\xfd\x1a\x91Z\x15\x08\xba{.\xf4" +
-	"<y\xe9B\xfb\x01\xd2\xe3\xc2\xcd<<x\xfe\x95\x93" +
-	"\xcee\"\x18S\xf8\xca\x08\x88\x10\x91\x011Bp7" +
-	"M\xa7\xcf$\xf7/\x1d%\x15\x81pG\x93*5y" +
-	"\xef\xe1w)\x10\xe0\x90ub\x9f\xf1\x08\x07\xafY'" +
-	"\xbe\x04\xe1\xef\x09\xfcu~\xc9\x89\x13z\x04u\xa1\xe0" +
-	"\xd0%\xda\xeb\xc6\x0a\x8dW\xcb\xb4N\x82;~\xf5\xfa" +
-	"\x07{\xdf\x14\xe78\xaf\xac\x0b\x96\x1c\xb2Q;l\xf4" +
-	"p\xf0\x9an\xed)\x10\xdc\xd6[\x9f\xdb/$\x8f_" +
-	"\xa4\xd9\x99+u\x1e\x08\x9c2\xc6*\xe5\x1c\x0c\xec&" +
-	"\xd4N\xa3\"\xb8\xa3\x8ck\x81\xe3\xc6\x9f\x81\xf9D\xc6" +
-	"T%x\xe7\xcb\xe6;W\x8f-\xbcB\x0dJ\xde\x1e" +
-	"\x1c5\x9e\x09\xf2jG\x90K>\xf4Kf\xb2gl" +
-	"\xde\xaf\xa4b\x80{\xf4\xd3\x1f\xe6\x8d\x1f9=9\x13" +
-	"<\x1c<e\xec\xad\x04?\x1f\xe4\xc4\xfe\xe3F\xe7\x9b" +
-	"\x08\xee3~\x0e\xce'Zs-x\x88\xcf\xb7h\xc7" +
-	"\x8d\xb5\xfb\xdf\x9e\xfe\xbda\xcd{\xc3\xa3\xc6\x810\xaf" +
-	"^\x0cs\xea\x8e\x8f\x8f\xb6\xf4\xcd\xef\xbd>7\xb8\xa2" +
-	"\xc6\xcd\xf0)c\xba\x12<\x15~\x8f\xe0F~\xec(" +
-	"\xac+\x97\xa6H\xdd\x0dYS}\xbb\x0cA\x83f\x8c" +
-	"5\xfdF0\x8e4M\x12\xdc\xa1|\xda\xca\xb6\xa5L" +
-	"Q\xc8\x15:\x92\x05+e\x9b\xd9\xb6m\xc3\x05\x8bh" +
-	"+\xa0\xe2\x10D\xfa\x83\x1dD\x80\xbe\xb2\x9d\x08B_" +
-	"\xd6E\x04\xa9\xb7l&\x82\xa6/\xec\"\xea,\xe5S" +
-	"\x19\xcbI\xf4e\xf3\xa9\xccHj\xd0,\xa6\xad\xe7\xdc" +
-	"~\xbb?_\xb09\x13\x8d\x94s\x99\\~w\xce\xa7" +
-	"\x03\xd3m\xb0\x8b\x15\x92{\xa4F\xa4\x81H\xff\xb6\x95" +
-	"H}-\xa1.\x09\xe8@\x1c\x0c^\xdcL\xa4\xbe\x93" +
-	"P?\x09\xe8Bx%M0xYB\xdd\x12\xd0\xa5" +
-	"\x8cC\x12\xe97;\x88\xd4\x1f\x12\xea\xb6\x00\xb484" +
-	"\"}\x8aS\xde\x90\xe8\x85\x80\x1e\xd0\xe2\x08\x10\xe9\xd3" +
-	"\x1cxK\"\xa91\x1a\x14q\x04\xd9\xfc\x18%Jj" +
-	"\x90H\xc6\x18\x0f\xc9x\xa5\x17\xcd\xd8E\x94\x8c0\xbe" +
-	"\x00\x02\xd1\x9c9d!B\x02\x11\x82\x9b\xcd\xa7L\xc7" +
-	"\xce\xe7\x88\xc8\xc7R\xf9\x9cc\xe5\x9c\x12cw\x11\xb6" +
-	"J Vk\x03\x81\xc1\xce\x82Y\xb4rNuO\xb4" +
-	"d\xef\xb1\xd0D\x02M\x84N3\x95\xcdX\xc3~\xbe" +
-	"\xa1|\xda\xee\xb7S&\x98h\x9b=d\x11!L\x02" +
-	"a\xe6*Z\x1e\x7f\x94\x1f\xf8\xf0,\xa1\x93\xe5\xbe\x0d" +
-	"\xd2.\xb2\xd6\x9a\xafu\xf3R\"\x15\x96Pq\x81\xd0" +
-	"\x1c\xb6\xda\xd6\xee\\>\x0d\x8bw.\xf0w\xbe\xc6\x92" +
-	"\x8eK\xa8c\x02\xd5&\xbd\xc1\xd8\xab\x12\xea\x84\x00\x04" +
-	"\xea<\xa8\xbf\xf54\x09]z\x1a\xeb\x07Y\xf9\x97$" +
-	"\xd4\xb8\x80\xaey\x02\xebc\xa3\xb5\x84\x95\x1e\x859\xe3" +
-	".\"uTB\x9d\x9c#\xf9,\xa9\\\xd3q\x8av" +
-	"_\xd9!i\x95\xfeo\xdd\x1e\xb7\xb3V\xa2\x8bM\xcd" +
-	"\x02\x84}\x01V\xf0a\xef\x93P\xab\xeal\xba\x92\xf5" +
-	"\\.\xa1\xd6\x0aD\x07\xcd\xd2 \x9aI\xa0\x99<q" +
-	"g\xd6s\xfaR\xb0R!\xdb\xcc*\x0d\xa8\x9b\xceh" +
-	"\x8d\xf2\x1b\xf8\x1f\x84>_k\x1d\x9f3\\\xb0\x10\xad" +
-	"\xe5  J\x88\xa6M\xc7lL\xbd\xc5\xcee\xbcW" +
-	"\xbc\xce\x11\x1d5Gt:fq\xc0r\x1a\x9b\xe2\xd1" +
-	"\xc7\xba\xdb\x12\xbd\xf6\xc0\xa03G\x97\xf6\x06e\xf6\x11" +
-	"\xa9\x07$\xd4C\x02\x89\"\xef\xf1s\x96KVq\xa0" +
-	"\x98/S\xa8`\xa7\x11\"\x81P\x03&\xaa\xa8\xe3\xcf" +
-	"a\x1d\xed\x1e\xb5Z\xec\xf3~\xc8\xbc\xefK\xa8\xb3u" +
-	"\xfd8\xc3\xe0i\x09u\x8e\x1d\xe9M\x8dOX\xb3\x8f" +
-	"$\xd4\x17<5\x8475>\xe3c\x9f\x9d\x19:\x1a" +
-	"\xbc\xb1qq\x11\x91\xfaFB]fK\"\x8e\x00\xa0" +
-	"\x7f\xbf\xb4n\x12U\xa6\x06\xa0O0xIB]\x11" +
-	"H\x94\xeb\x8d\x9a\x18\x98e[>X\xf5\x94\x9d\x15%" +
-	"J\xb5\xe9\xe0\x9f\xcf\x9b\x0e\xd2NW-\x19*\xdbi" +
-	"\x04H \xe0V\x7fD\x14\x1ah\x84\xdei_\xaf\xc7" +
-	"u-\xea\xad\xb9\xa6\xaa\xd4\xea\x8e\x99\x16m\x12p+" +
-	"3<i\xef!\xd4\x8a\xad`u\xc5\xfa7\xb3W\xec" +
-	"l\xd6\xed%\xab\x98x\xa2\x98/\x17\x989\xe23o" +
-	"d\xed\xd7K\xa8-uM\xea\xe6&m\x90P[k" +
-	"M\xea\xe1r6I\xa8ms^\xfc\x84=\x9c\xefN" +
-	"W\xffu\xf2\xbf\x9c\xe3\x0f\x82Y7\x19\x8f-\xab\xcd" +
-	"\x9b\x0d\xd1\xb2c\x95TLj\x8b]\x17\x1e\x85\xc9=" +
-	"\xdb)\xa1\x06\x05Z\xf0\x0f\xc3\xec\x04\x8bK|VB" +
-	"e\x05Z\xc4\xb4;\xe3\x05\x9b\xe1\xb4\x84*\x08\xb4\xc8" +
-	"\xdb\x0c\xf3%2\xd4E\xa4\x06%\x94#\x10J\xdbE" +
-	"\xc4\xaa\x9fB\x04\xc4\x08\xd1~;k!V\xbb\xe7g" +
-	"\xe0\xac\x9d\xcb V\xfb\x0a\xf1\xe0\x91\x92w\xf9\"V" +
-	"\xffi\xc6O\xfe\x0d\x00\x00\xff\xff\x8e\x9dO\xa6"
+const schema_ae9223e76351538a = "x\xda\xb4Va\x88T_\x15?\xbf{\xdf\xec\x1b\xd7" +
+	"\xd9f^o\xc4\x14\x97\xf9\xff\xff\xfd\x17\xdc\xc5]\xdc" +
+	"u\x83\x1a\x82\xd1\xcdjW\x94\xbc\x8eB\x85\x1fz;" +
+	"\xf3v\xf79\xb33\xc3\xcc\x1bm\x85X2\x14Z\x08" +
+	"Q\xb4P\x8c\xd4$\xfaTa\x06IJ\x1a\xf9\xa1 " +
+	"\x09I\xd0\xd0(\xd2V\xc2$Ii\x05\xed\xc5y\xb3" +
+	"\xf3\xe6\xcd:B_\x9aOw~\xef\xdc\xf3;\xf7\xfc" +
+	"\xce9\xf7n>+\xb7\x8a\xe1\xc8\xbcF\xa4F#]" +
+	"\xde\xe1;\xbb\xbep\xef\xce\xc87\xc9H\x0a\xaf\xf0\xe9" +
+	"\x99\xdb\xdf\xf9\xa1\xfb\x80\x08f\x9f\xf8\xad9,t\"" +
+	"sP\xcc\x13\xbc\xf17\xf9\xab\xd9c\x1f,\x90\x8aA" +
+	"x\x0bY\x95[\xfc\xf8\xc9\x1fQ\xa4\x8bMlq\xc4" +
+	"t\xd8x\x8b-<\x10\xfe\xfd\x10\xff\xba\xfd\xfe\xa5K" +
+	"F\x0c!S\xb0\xe9\x19\xed\xacyA\xe3\xd5w\xb5\x0c" +
+	"\xc1;\xf5\xf8\xd9O\xbf\xfe=q\x83\xfdj!c\xdf" +
+	"\xe4\xbav\xd2\xbc\xc5\xab-75\xf6\xeb\x0d,\xfd\xca" +
+	"\xf9F\xf6\xe2]j\xf7\xec\xc7y\xab\xeb\xb2\xf9;?" +
+	"\x9c\xdft\x1d\"x;\xfa\x97\xd6\x9f\xbf\xe0\xdc\xefd" +
+	"\xdb\xa7\x9f4\x07u^\xf5\xebl\x1b\x9c\\\xc5\xf0V" +
+	"\xc8G\xf5\x8b\xe6\xb7\xf4\xb5D\xe6i\xdfx\xff\xb7\xad" +
+	"\x1f<>\xbf\xee\x11u8\xdes}\xc1|\xe5;~" +
+	"\xa9\xf3\xf1\x8e\xff\xb5\xb0\xb8\xeb\xc4\x9a\xbf\x91J\x00\xde" +
+	"\xb9_\xfeq\xcd\xa9\xd3W\x16\x97\x8d\xd7D/\x9b\xbd" +
+	"Q^\xad\x8b\x1e\xa2\xd0g\x15\x83\x0cy\x96l2\x17" +
+	"=b~-\xba\x96h\xcb\xd1\xe8q\x10\xbc\xf5_z" +
+	"1z\xec\xfbo\x9ev\x8c\xb9\xb7{\xc1\xec\xeb\xe6\xd5" +
+	"\xfb\xdd\xec:\xfd\x8bs\xbd\x93k\xf7<[i\xecg" +
+	"\xe3D\xf7e\xf3\x8co|\xba\xfb\xc7\x04/\xf6\xa7t" +
+	"\xe5S\xf5\xda+R\x1f\x85\xd6Rh\x9f\xa6C\x83f" +
+	"\x0e\xae\xfe;\xc1\x1c^\xbdH\xf0f\xcby\xbb8\x94" +
+	"\xb3D\xa5TIg+v\xce\xb1\x8aC{\xe7*6" +
+	"\xd1n@%!\x88\x8cO\xa4\x89\x00cp\x84\x08\xc2" +
+	"\xe8\x1b#\x824zw\x10A3\xd6\x8d\x11ej\xe5" +
+	"\\\xc1vS\x93\xc5r\xae0\x9f\x9b\xb1\xaay\xfb\xa0" +
+	"7\xe5L\x95+\x0e{\xa2\xf9z\xa9P*\x1f*\x05" +
+	"t`\xba\xedN\xd5'\xf9PjD\x1a\x88\x8c\x7f\x0c" +
+	"\x10\xa9'\x12\xea\x85\x80\x01$\xc1\xe0\xf3\x1dD\xea\x9f" +
+	"\x12\xea\xb5\x80!D#\xa4W\x0c.Id\x93\x100" +
+	"\xa4LB\x12\x99\x06\xd2D\xd9\x18$\xb2\x1f\x83\x00\xb4" +
+	"$4\xd6\x09\x03D\xd9\x04\xc3\x1b\xd8<\xa2%\x11a" +
+	"\xd5|\xf3$\xe3\xef1\xde%\x92\xe8\xe2\xe4c\x81(" +
+	"\xfb\x1e\xe3\x9b\x18\xd7e\xd2\x17\xa5\x1f\x07\x88\xb2\x1b\x19" +
+	"\x1fe<\x1aI\"Jd\x0e\xfb~61>\x0e\x81" +
+	"x\xc9\x9a\xb5\x11#\x81\x18\xc1+\x96s\x96\xeb\x94K" +
+	"D\x14`\xb9r\xc9\xb5Kn\x8d\xb1\x8f\x10vK " +
+	"\xd1\xd2\x89\xc0`\xa6bU\xed\x92\xdb\xdc\x13\xaf9\x87" +
+	"m\xac\"\x81U\x84\x8c\x95+\x16\xec\xb9\xc0\xdfl9" +
+	"\xefL99\x0bL\xb4\xd7\x99\xb5\x89\x10%\x81(s" +
+	"U\xed\x06\x7f\x9c?4\xe1\xccW-\xd7\xad\xd6Z\xec" +
+	"A\xb75\xd8\xdb\x95\xca\xd6'\xb7K\xa7\xcabi\x81" +
+	"X=\x1f\x10\xa9\xa8\x84J\x0a\xe8+\xa2im\x9d(" +
+	"\x95\xf3\xb0\xdf-s\xa02cO%\xd4\x92\x00\x04B" +
+	"El\xbc\xfc2\x09C6\xb41\xfe\x92&R\x0f$" +
+	"\xd4\x13\x01Ck\x08c<^\x08\xd5\x0d\xab\x1be\x8f" +
+	"\x07Bu\xd3%\x93X\xc5u\xc3\xdb_Hd\x13M" +
+	"a\xbb\x89\xcc\x1e\x8c\x11e\xa3h\xd6\x13\x0b\xbb\xda\xaf" +
+	"\xa7\x91p=\xb5\x09\xdb&\x88\xc7\xd9t&\xeb.I" +
+	"\xbb\xf6\xffWg\xde)\x95\xf3\xf6D\xbeI\x9f*\x15" +
+	"\x9dR!p\xda&\xc0\xe7\x9c\xa2\x9d\x1a\xe3\xced\x11" +
+	"\xa2\x81\x08\xfd\x9c\xf0\x0f%\xd4\xe6P\xaf\x0d\xb2\xa6\x1b" +
+	"%\xd4\xa8@|\xc6\xaa\xcd\xa0\x87\x04z\xa8!\xf0\xf2" +
+	"\xba\xdd\xff\x17\xb7\xb9.\xaa\xff\x8b\xef\x91\x90\xefp&" +
+	"S\x07\xadb\xdd\xee\xec\x9dG\x92\xeeXE\xa5\x01\xa1" +
+	"\xcb\x0e\x03q\x1eR\xef\xa0\x0c\x18\x07B\x8c\xee\\\xc5" +
+	"F\xbc\xe5\x83\x808!\x9e\xb7\\\xab3\xf5N\xa7T" +
+	" ZQ\xf3\xe9V\xcdg\\\xab:m\xbb\x9d\xcb~" +
+	"\xdbg&\x86R{\x9c\xe9\x19wEfF:\x849" +
+	"I\xa46I\xa8O\x0a\xa4\xaa\xbc'\xf0Y\xaf\xd9\xd5" +
+	"\xe9j\xb9Nz\xc5\xc9C'\x01\xbd\x03\x13\xf9\xd9\x09" +
+	"\xae*\x03#\x0dj\xb5!\xe0\xfd\x19\xf3\xfeDB]" +
+	"\x0b)r\x95\xc1+\x12\xea\x06\xf7\\c\xb0^\xe7\x9c" +
+	"\xfd\\B\xfd\x9a\xe7\xaa\xf0\xe7\xaaq\x93\x8f}MB" +
+	"\xdd\xe3\xa6\x83?U\x8d\xbb\xeb\x89\xd4\xef%\xd4\x03n" +
+	":$\x11\x01\x8c\xfb\\@\x7f\x90P\x7fn\xceS\xc0" +
+	"x\xc8\xe0=\x09\xf5H Uo\x93~\xba\xad\xa5\xf8" +
+	"`\xcdSf\xfcL\x84z 8_\xa3\x07\xa4\x93o" +
+	"\x16\xbc^w\xf2\x88\x90@\xc4k\xfe\x88H\x9f\xee\x84" +
+	"\xbe\xdd\x1cD+$\xda\xd3\xaa\x9af\xa6\x86\xd3\xcb\x12" +
+	"\x8d\x0bx\xfe5\x97u\x0e\x13Z\xc1\xfaX(\xd8\xe0" +
+	"\xa1\xd3i\x9c\xee\xab\xd9\xd5\xd4\xe7\xab\xe5z\x85\x99c" +
+	"\x01\xf3g9\xf7[%\xd4\xce\x90H\x13,\xd2v\x09" +
+	"\xb5\xbb%\xd2.\x0eg\\B\xed]\xd9J\xce\\y" +
+	"\"\xdf\xfc\x97\xe1\x7f%7\x18Rm\x97=\x0ff{" +
+	"\xa81\xb7\xe2u\xd7\xae\xa9\x84\xd46x\x1e\x1a\x14\x16" +
+	"k\xb6_B\xcd\x08\xf4\xe2?\x1e\x1a\x95`s\x88_" +
+	"\x91PE\x81^\xf1\xc6[\xae\x05\x87\xe1\xbc\x84\xaa\x08" +
+	"\xf4\xca\xd7\x0cG\x88\x8c\xd91\"5#\xa1\\\x01=" +
+	"\xefT\x91h\xbe,\x09H\x10\xe2SN\xd1F\xa2\xf5" +
+	"\x14Z\x86\xfdi\x96h=\xd4\x1a\xf0|\xad\xf1>A" +
+	"\"\xfc\xd2\xe5/\xff\x1d\x00\xad_\x89\xea"
 
 func init() {
 	schemas.Register(schema_ae9223e76351538a,
@@ -1346,6 +1503,7 @@ func init() {
 		0xa4a421ce00f301dd,
 		0xc0029f81b3eee594,
 		0xd5a2538369c2f82a,
+		0xd969a1a01af8294a,
 		0xdc74a897ce683c6b,
 		0xe419a0e5a661965c,
 		0xe615914de76be38f,
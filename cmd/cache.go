@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/codegangsta/cli"
+	"github.com/threefoldtech/0-fs/meta"
+	"github.com/threefoldtech/0-fs/rofs"
+)
+
+//liveSet returns the cache ids of all files in the given flists
+func liveSet(flists []string) (map[string]struct{}, error) {
+	live := make(map[string]struct{})
+	for _, flist := range flists {
+		db, err := getDB(flist)
+		if err != nil {
+			return nil, err
+		}
+
+		store, err := meta.NewStore(db)
+		if err != nil {
+			return nil, err
+		}
+
+		walker, ok := store.(meta.Walker)
+		if !ok {
+			store.Close()
+			return nil, fmt.Errorf("meta store of '%s' can't be walked", flist)
+		}
+
+		err = rofs.LiveSet(walker, live)
+		store.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk '%s': %s", flist, err)
+		}
+	}
+
+	return live, nil
+}
+
+func cacheGC(ctx *cli.Context) error {
+	cache := ctx.String("cache")
+	if len(cache) == 0 {
+		return fmt.Errorf("--cache is required")
+	}
+
+	flists := ctx.Args()
+	if len(flists) == 0 && !ctx.Bool("all") {
+		return fmt.Errorf("expecting one or more flists to keep, use --all to clean up the full cache")
+	}
+
+	live, err := liveSet(flists)
+	if err != nil {
+		return err
+	}
+
+	dryRun := ctx.Bool("dry-run")
+	var files, inuse int
+	var size uint64
+	err = rofs.Unreferenced(cache, live, func(name string, info os.FileInfo) error {
+		if dryRun {
+			fmt.Println(name)
+			files++
+			size += uint64(info.Size())
+			return nil
+		}
+
+		removed, err := rofs.Evict(name)
+		if err != nil {
+			log.Errorf("failed to remove '%s': %s", name, err)
+			return nil
+		} else if !removed {
+			log.Debugf("skipping '%s': file is in use", name)
+			inuse++
+			return nil
+		}
+
+		log.Debugf("removed '%s'", name)
+		files++
+		size += uint64(info.Size())
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		log.Infof("%d unreferenced files (%d bytes)", files, size)
+	} else {
+		log.Infof("removed %d unreferenced files (%d bytes), %d files in use were skipped", files, size, inuse)
+	}
+
+	return nil
+}
+
+func cacheVerify(ctx *cli.Context) error {
+	cache := ctx.String("cache")
+	if len(cache) == 0 {
+		return fmt.Errorf("--cache is required")
+	}
+
+	if len(ctx.Args()) == 0 {
+		return fmt.Errorf("expecting one or more flists")
+	}
+
+	cmd := Cmd{
+		Meta:   ctx.Args(),
+		URL:    ctx.String("storage-url"),
+		Router: ctx.String("local-router"),
+	}
+
+	repair := ctx.Bool("repair")
+	metaStore, err := getMetaStore(cmd.Meta)
+	if err != nil {
+		return err
+	}
+
+	defer metaStore.Close()
+
+	var files, corrupt int
+	var paths []string
+	err = rofs.Verify(metaStore, cache, repair, func(result rofs.VerifyResult) {
+		if !result.Cached {
+			return
+		}
+
+		files++
+		if len(result.Corrupt) == 0 {
+			return
+		}
+
+		corrupt++
+		paths = append(paths, result.Path)
+		fmt.Printf("%s: %d corrupt blocks %v\n", result.Path, len(result.Corrupt), result.Corrupt)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("verified %d cached files, %d corrupt", files, corrupt)
+	if !repair || len(paths) == 0 {
+		if corrupt != 0 {
+			return fmt.Errorf("cache has %d corrupt files", corrupt)
+		}
+
+		return nil
+	}
+
+	//cmd.Meta now holds the flists extracted by getMetaStore
+	dataStore, err := getDataStoreFromCmd(&cmd)
+	if err != nil {
+		return err
+	}
+
+	defer dataStore.Close()
+
+	log.Infof("downloading corrupt blocks")
+	return rofs.NewPrefetcher(dataStore, metaStore, cache).Paths(context.Background(), paths)
+}
+
+var cacheCmd = cli.Command{
+	Name:  "cache",
+	Usage: "manage a cache directory",
+	Subcommands: []cli.Command{
+		{
+			Name:      "gc",
+			Usage:     "remove the cache files that are not used by any of the given flists",
+			ArgsUsage: "<flist>...",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache",
+					Usage: "cache directory to clean up",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "only print the files that would be removed",
+				},
+				cli.BoolFlag{
+					Name:  "all",
+					Usage: "allow running with no flists, which removes all files that are not in use",
+				},
+			},
+			Action: cacheGC,
+		},
+		{
+			Name:      "verify",
+			Usage:     "verify the cached files of the given flists against their blocks hashes",
+			ArgsUsage: "<flist>...",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache",
+					Usage: "cache directory to verify",
+				},
+				cli.BoolFlag{
+					Name:  "repair",
+					Usage: "download the corrupt blocks again",
+				},
+				cli.StringFlag{
+					Name:  "storage-url",
+					Value: "zdb://hub.grid.tf:9900",
+					Usage: "fallback storage url in case no router.yaml available in flist, used with --repair",
+				},
+				cli.StringFlag{
+					Name:  "local-router",
+					Usage: "path to local router.yaml to merge with the router.yaml from the flist, used with --repair",
+				},
+			},
+			Action: cacheVerify,
+		},
+	},
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/codegangsta/cli"
+	"github.com/threefoldtech/0-fs/meta"
+	"github.com/threefoldtech/0-fs/meta/writer"
+	"github.com/threefoldtech/0-fs/storage"
+	"github.com/threefoldtech/0-fs/storage/router"
+)
+
+func getWriteStore(url, routerPath string) (*router.Router, error) {
+	if len(routerPath) != 0 {
+		cfg, err := router.NewConfigFromFile(routerPath)
+		if err != nil {
+			return nil, err
+		}
+
+		return cfg.Router(nil)
+	}
+
+	if len(url) == 0 {
+		return nil, fmt.Errorf("--storage-url or --router is required")
+	}
+
+	return storage.NewSimpleStorage(url)
+}
+
+func create(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("expecting a single source directory argument")
+	}
+
+	output := ctx.String("output")
+	if len(output) == 0 {
+		return fmt.Errorf("--output is required")
+	}
+
+	routerPath := ctx.String("router")
+	dataStore, err := getWriteStore(ctx.String("storage-url"), routerPath)
+	if err != nil {
+		return err
+	}
+
+	defer dataStore.Close()
+
+	tmp, err := ioutil.TempDir("", "flist-")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tmp)
+
+	w, err := writer.New(tmp, dataStore, writer.WithBlockSize(ctx.Uint("block-size")))
+	if err != nil {
+		return err
+	}
+
+	if err := w.Add(args.First()); err != nil {
+		w.Discard()
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if len(routerPath) != 0 {
+		//ship the router.yaml with the flist so mounts know where to find the blocks
+		data, err := ioutil.ReadFile(routerPath)
+		if err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(path.Join(tmp, "router.yaml"), data, 0644); err != nil {
+			return err
+		}
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+
+	if err := meta.Pack(tmp, out); err != nil {
+		//don't leave a truncated archive behind
+		out.Close()
+		os.Remove(output)
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(output)
+		return err
+	}
+
+	log.Infof("flist created at '%s'", output)
+	return nil
+}
+
+var createCmd = cli.Command{
+	Name:      "create",
+	Usage:     "create an flist from a local directory and upload its blocks",
+	ArgsUsage: "<source-dir>",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "output,o",
+			Usage: "path of the flist file to create",
+		},
+		cli.StringFlag{
+			Name:  "storage-url",
+			Usage: "storage url to upload blocks to",
+		},
+		cli.StringFlag{
+			Name:  "router",
+			Usage: "path to a router.yaml to upload blocks with, the file is also shipped with the flist",
+		},
+		cli.UintFlag{
+			Name:  "block-size",
+			Value: writer.DefaultBlockSize,
+			Usage: "size of file blocks in KB",
+		},
+	},
+	Action: create,
+}
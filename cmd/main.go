@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/codegangsta/cli"
 	"github.com/op/go-logging"
@@ -25,6 +26,23 @@ type Cmd struct {
 	PidPath  string
 	LogPath  string
 	ReadOnly bool
+	Metrics  string
+
+	RecordTrace bool
+	ReplayTrace string
+	VerifyCache bool
+	BlockCache  bool
+	InodeFS     bool
+
+	CacheSize          uint64
+	CacheLowWatermark  uint
+	CacheHighWatermark uint
+
+	AttrTimeout     time.Duration
+	EntryTimeout    time.Duration
+	NegativeTimeout time.Duration
+	MaxReadAhead    int
+	MaxBackground   int
 }
 
 // Validate command
@@ -52,7 +70,39 @@ func action(ctx *cli.Context) error {
 		PidPath:  ctx.GlobalString("pid"),
 		LogPath:  ctx.GlobalString("log"),
 		ReadOnly: ctx.GlobalBool("ro"),
+		Metrics:  ctx.GlobalString("metrics-listen"),
+
+		RecordTrace: ctx.GlobalBool("record-trace"),
+		ReplayTrace: ctx.GlobalString("replay-trace"),
+		VerifyCache: ctx.GlobalBool("verify-cache"),
+		BlockCache:  ctx.GlobalBool("block-cache"),
+		InodeFS:     ctx.GlobalBool("inode-fs"),
+
+		CacheLowWatermark:  ctx.GlobalUint("cache-low-watermark"),
+		CacheHighWatermark: ctx.GlobalUint("cache-high-watermark"),
+
+		AttrTimeout:     ctx.GlobalDuration("attr-timeout"),
+		EntryTimeout:    ctx.GlobalDuration("entry-timeout"),
+		NegativeTimeout: ctx.GlobalDuration("negative-timeout"),
+		MaxBackground:   ctx.GlobalInt("max-background"),
+	}
+
+	if size := ctx.GlobalString("cache-size"); len(size) != 0 {
+		var err error
+		if cmd.CacheSize, err = parseSize(size); err != nil {
+			return fmt.Errorf("invalid --cache-size: %s", err)
+		}
+	}
+
+	if size := ctx.GlobalString("max-readahead"); len(size) != 0 {
+		readahead, err := parseSize(size)
+		if err != nil {
+			return fmt.Errorf("invalid --max-readahead: %s", err)
+		}
+
+		cmd.MaxReadAhead = int(readahead)
 	}
+
 	errs := cmd.Validate()
 	var buf strings.Builder
 	for _, err := range errs {
@@ -95,6 +145,20 @@ func main() {
 				Name:  "cache",
 				Usage: "external (common) cache directory, if not provided a temporary cache location will be created under `backend`",
 			},
+			cli.StringFlag{
+				Name:  "cache-size",
+				Usage: "maximum size of the cache directory (ex: 10G), least recently used files are evicted once the cache is full. Unlimited if not set",
+			},
+			cli.UintFlag{
+				Name:  "cache-high-watermark",
+				Value: 90,
+				Usage: "cache usage in percent of --cache-size that starts an eviction",
+			},
+			cli.UintFlag{
+				Name:  "cache-low-watermark",
+				Value: 80,
+				Usage: "cache usage in percent of --cache-size that stops an eviction",
+			},
 			cli.StringFlag{
 				Name:  "storage-url",
 				Value: "zdb://hub.grid.tf:9900",
@@ -120,6 +184,52 @@ func main() {
 				Name:  "pid",
 				Usage: "when starting as a daemon, location of the pid file",
 			},
+			cli.BoolFlag{
+				Name:  "block-cache",
+				Usage: "keep each block once in the cache instead of a copy of each file, saves space when flists share files",
+			},
+			cli.BoolFlag{
+				Name:  "inode-fs",
+				Usage: "serve the flist with the inode based fuse implementation, lookups are cached by the kernel",
+			},
+			cli.DurationFlag{
+				Name:  "attr-timeout",
+				Value: g8ufs.DefaultAttrTimeout,
+				Usage: "how long the kernel caches the attributes of the files, flists are immutable so it can be long (ex: 1h)",
+			},
+			cli.DurationFlag{
+				Name:  "entry-timeout",
+				Value: g8ufs.DefaultEntryTimeout,
+				Usage: "how long the kernel caches the directory entries",
+			},
+			cli.DurationFlag{
+				Name:  "negative-timeout",
+				Usage: "how long the kernel caches that a directory entry doesn't exist",
+			},
+			cli.StringFlag{
+				Name:  "max-readahead",
+				Usage: "maximum read ahead size (ex: 1M), capped by the kernel. Kernel default if not set",
+			},
+			cli.IntFlag{
+				Name:  "max-background",
+				Usage: "maximum number of pending background requests. Default (12) if not set",
+			},
+			cli.BoolFlag{
+				Name:  "verify-cache",
+				Usage: "verify cached files the first time they are opened, corrupt blocks are downloaded again",
+			},
+			cli.BoolFlag{
+				Name:  "record-trace",
+				Usage: "record the first access of each file to `backend`/access.trace",
+			},
+			cli.StringFlag{
+				Name:  "replay-trace",
+				Usage: "path to an access trace recorded by a previous mount, the files in the trace are downloaded in the background after mounting",
+			},
+			cli.StringFlag{
+				Name:  "metrics-listen",
+				Usage: "serve prometheus metrics on this address (ex: :9100) under /metrics",
+			},
 		},
 
 		Before: func(ctx *cli.Context) error {
@@ -150,6 +260,11 @@ func main() {
 			return nil
 		},
 		Action: action,
+		Commands: []cli.Command{
+			createCmd,
+			cacheCmd,
+			prefetchCmd,
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+//serveMetrics exposes the prometheus metrics on listen in the background
+func serveMetrics(listen string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	go func() {
+		log.Infof("serving metrics on %s", listen)
+		if err := http.ListenAndServe(listen, mux); err != nil {
+			log.Errorf("failed to serve metrics: %s", err)
+		}
+	}()
+}
@@ -12,23 +12,24 @@ import (
 
 	"github.com/sevlyar/go-daemon"
 	"github.com/threefoldtech/0-fs/meta"
+	"github.com/threefoldtech/0-fs/storage/router"
 
 	g8ufs "github.com/threefoldtech/0-fs"
 )
 
-func start(cmd *Cmd, target string) (*g8ufs.G8ufs, error) {
+func start(cmd *Cmd, target string) (*g8ufs.G8ufs, *router.Router, error) {
 	// Test if the meta path is a directory
 	// if not, it's maybe a flist/tar.gz
 
 	metaStore, dataStore, err := getStoresFromCmd(cmd)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	log.Debug("router\n", dataStore)
 
-	return g8ufs.Mount(&g8ufs.Options{
+	fs, err := g8ufs.Mount(&g8ufs.Options{
 		Store:    metaStore,
 		Backend:  cmd.Backend,
 		Cache:    cmd.Cache,
@@ -36,7 +37,30 @@ func start(cmd *Cmd, target string) (*g8ufs.G8ufs, error) {
 		Storage:  dataStore,
 		Reset:    cmd.Reset,
 		ReadOnly: cmd.ReadOnly,
+
+		RecordTrace: cmd.RecordTrace,
+		ReplayTrace: cmd.ReplayTrace,
+		VerifyCache: cmd.VerifyCache,
+		BlockCache:  cmd.BlockCache,
+		InodeFS:     cmd.InodeFS,
+
+		CacheSize:          cmd.CacheSize,
+		CacheLowWatermark:  float64(cmd.CacheLowWatermark) / 100,
+		CacheHighWatermark: float64(cmd.CacheHighWatermark) / 100,
+
+		AttrTimeout:     cmd.AttrTimeout,
+		EntryTimeout:    cmd.EntryTimeout,
+		NegativeTimeout: cmd.NegativeTimeout,
+		MaxReadAhead:    cmd.MaxReadAhead,
+		MaxBackground:   cmd.MaxBackground,
 	})
+
+	if err != nil {
+		dataStore.Close()
+		return nil, nil, err
+	}
+
+	return fs, dataStore, nil
 }
 
 func reload(fs *g8ufs.G8ufs, cmd *Cmd) error {
@@ -57,7 +81,7 @@ func reload(fs *g8ufs.G8ufs, cmd *Cmd) error {
 	}
 
 	// - first use the ones passed via command line
-	metaStore, _, err := getStoresFromCmd(cmd)
+	metaStore, err := getMetaStore(cmd.Meta)
 	if err != nil {
 		return err
 	}
@@ -124,11 +148,17 @@ func mount(cmd *Cmd, target string) error {
 
 	defer cntxt.Release()
 
-	fs, err = start(cmd, target)
+	if len(cmd.Metrics) != 0 {
+		serveMetrics(cmd.Metrics)
+	}
+
+	fs, dataStore, err := start(cmd, target)
 	if err != nil {
 		return err
 	}
 
+	defer dataStore.Close()
+
 	// this line is very important because it works as
 	// a signal to core0 that the rootfs of the container
 	// is ready and then proceed with starting the container
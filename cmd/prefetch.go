@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/threefoldtech/0-fs/rofs"
+)
+
+const (
+	progressInterval = 5 * time.Second
+)
+
+func prefetch(ctx *cli.Context) error {
+	cache := ctx.String("cache")
+	if len(cache) == 0 {
+		return fmt.Errorf("--cache is required")
+	}
+
+	if len(ctx.Args()) == 0 {
+		return fmt.Errorf("expecting one or more flists")
+	}
+
+	cmd := Cmd{
+		Meta:   ctx.Args(),
+		URL:    ctx.String("storage-url"),
+		Router: ctx.String("local-router"),
+	}
+
+	metaStore, dataStore, err := getStoresFromCmd(&cmd)
+	if err != nil {
+		return err
+	}
+
+	defer metaStore.Close()
+	defer dataStore.Close()
+
+	if err := os.MkdirAll(cache, 0755); err != nil {
+		return err
+	}
+
+	var last time.Time
+	opts := []rofs.PrefetchOption{
+		rofs.WithPrefetchWorkers(ctx.Uint("workers")),
+		rofs.WithPatterns(ctx.StringSlice("pattern")...),
+		rofs.WithProgress(func(p rofs.Progress) {
+			if time.Since(last) < progressInterval && p.Files != p.TotalFiles {
+				return
+			}
+
+			last = time.Now()
+			log.Infof("prefetched %d/%d files (%d/%d bytes, %d errors)",
+				p.Files, p.TotalFiles, p.Bytes, p.TotalBytes, p.Errors)
+		}),
+	}
+
+	if ctx.Bool("block-cache") {
+		opts = append(opts, rofs.WithBlockCache())
+	}
+
+	prefetcher := rofs.NewPrefetcher(dataStore, metaStore, cache, opts...)
+
+	background, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sig)
+	go func() {
+		if _, ok := <-sig; ok {
+			log.Info("interrupted, run prefetch again to resume")
+			cancel()
+		}
+	}()
+
+	accessList := ctx.String("access-list")
+	if len(accessList) == 0 {
+		return prefetcher.Walk(background)
+	}
+
+	file, err := os.Open(accessList)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+	paths, err := rofs.ReadAccessList(file)
+	if err != nil {
+		return err
+	}
+
+	return prefetcher.Paths(background, paths)
+}
+
+var prefetchCmd = cli.Command{
+	Name:      "prefetch",
+	Usage:     "download the files of one or more flists into a cache directory",
+	ArgsUsage: "<flist>...",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "cache",
+			Usage: "cache directory to download the files to, use the same directory as --cache when mounting",
+		},
+		cli.StringFlag{
+			Name:  "storage-url",
+			Value: "zdb://hub.grid.tf:9900",
+			Usage: "fallback storage url in case no router.yaml available in flist",
+		},
+		cli.StringFlag{
+			Name:  "local-router",
+			Usage: "path to local router.yaml to merge with the router.yaml from the flist",
+		},
+		cli.StringSliceFlag{
+			Name:  "pattern",
+			Usage: "only prefetch files matching this glob pattern, can appear many times. A pattern that matches a directory matches all files under it",
+		},
+		cli.StringFlag{
+			Name:  "access-list",
+			Usage: "only prefetch the files listed in this file (one path per line) in the same order",
+		},
+		cli.BoolFlag{
+			Name:  "block-cache",
+			Usage: "download into the block cache, for mounts that use --block-cache",
+		},
+		cli.UintFlag{
+			Name:  "workers",
+			Value: rofs.DefaultPrefetchWorkers,
+			Usage: "number of blocks to download in parallel",
+		},
+	},
+	Action: prefetch,
+}
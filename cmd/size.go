@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var sizeUnits = map[string]uint64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+//parseSize parses a size in bytes with an optional K, M, G or T (binary) unit suffix
+func parseSize(s string) (uint64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+
+	unit := ""
+	if i >= 0 {
+		unit = s[i:]
+		s = s[:i]
+	}
+
+	multiplier, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit '%s'", unit)
+	}
+
+	value, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %s", err)
+	}
+
+	if value > math.MaxUint64/multiplier {
+		return 0, fmt.Errorf("size '%d%s' is too large", value, unit)
+	}
+
+	return value * multiplier, nil
+}
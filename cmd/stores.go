@@ -105,13 +105,9 @@ func layerLocalStore(local string, store *router.Router) (*router.Router, error)
 	return router.Merge(localRouter, store), nil
 }
 
-//getStoresFromCmd helper function to initialize stores from cmd line
-func getStoresFromCmd(cmd *Cmd) (metaStore meta.Store, dataStore *router.Router, err error) {
-	metaStore, err = getMetaStore(cmd.Meta)
-	if err != nil {
-		return
-	}
-
+//getDataStoreFromCmd helper function to initialize the data store from cmd line,
+//cmd.Meta must already hold the extracted flists (see getMetaStore)
+func getDataStoreFromCmd(cmd *Cmd) (dataStore *router.Router, err error) {
 	if len(cmd.URL) != 0 {
 		//prepare the fallback storage
 		dataStore, err = storage.NewSimpleStorage(cmd.URL)
@@ -127,10 +123,16 @@ func getStoresFromCmd(cmd *Cmd) (metaStore meta.Store, dataStore *router.Router,
 	}
 
 	//finally merge with local router.yaml
-	dataStore, err = layerLocalStore(cmd.Router, dataStore)
+	return layerLocalStore(cmd.Router, dataStore)
+}
+
+//getStoresFromCmd helper function to initialize stores from cmd line
+func getStoresFromCmd(cmd *Cmd) (metaStore meta.Store, dataStore *router.Router, err error) {
+	metaStore, err = getMetaStore(cmd.Meta)
 	if err != nil {
 		return
 	}
 
+	dataStore, err = getDataStoreFromCmd(cmd)
 	return
 }
@@ -1,6 +1,7 @@
 package g8ufs
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,6 +10,7 @@ import (
 	"syscall"
 	"time"
 
+	fusefs "github.com/hanwen/go-fuse/v2/fs"
 	"github.com/hanwen/go-fuse/v2/fuse"
 	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
 	"github.com/hanwen/go-fuse/v2/fuse/pathfs"
@@ -49,35 +51,127 @@ type Options struct {
 	Reset bool
 	//Mount fs read-only
 	ReadOnly bool
+	//CacheSize (optional) maximum size of the cache in bytes, least recently used files
+	//are evicted once the cache grows above the high watermark. 0 means no limit
+	CacheSize uint64
+	//CacheLowWatermark and CacheHighWatermark are fractions of the CacheSize. Eviction
+	//starts above the high watermark and stops below the low watermark. If not set
+	//rofs.DefaultLowWatermark and rofs.DefaultHighWatermark are used
+	CacheLowWatermark  float64
+	CacheHighWatermark float64
+	//VerifyCache if set, cache files are verified against the blocks hashes the first time
+	//they are opened, and corrupt blocks are downloaded again
+	VerifyCache bool
+	//BlockCache if set, the cache keeps each block once instead of a copy of
+	//each file, which saves space when files share blocks (across flists)
+	BlockCache bool
+	//RecordTrace if set, the first access of each file is recorded to TraceFile under
+	//the backend directory
+	RecordTrace bool
+	//ReplayTrace (optional) path to a trace recorded by a previous mount. The files
+	//in the trace are downloaded in the background right after mounting
+	ReplayTrace string
+	//InodeFS if set, the read-only layer is served with the inode based go-fuse API
+	//(fs) instead of the path based one (pathfs). Lookups are done in the parent
+	//directory instead of the store, and readdirplus is supported
+	InodeFS bool
+	//AttrTimeout and EntryTimeout are how long the kernel caches the attributes
+	//and the entries (dentries) of the flist. If not set DefaultAttrTimeout and
+	//DefaultEntryTimeout are used. Flists are immutable, so they can be long, the
+	//kernel caches are invalidated when the meta store is changed (SetMetaStore)
+	AttrTimeout  time.Duration
+	EntryTimeout time.Duration
+	//NegativeTimeout is how long the kernel caches that an entry doesn't exist
+	NegativeTimeout time.Duration
+	//MaxReadAhead (optional) maximum read ahead size in bytes, capped by the kernel
+	MaxReadAhead int
+	//MaxBackground (optional) maximum number of pending background (async I/O) requests
+	MaxBackground int
 }
 
+const (
+	//TraceFile is the name of the access trace file under the backend directory
+	TraceFile = "access.trace"
+
+	//DefaultAttrTimeout is the attributes kernel cache timeout if not set in the options
+	DefaultAttrTimeout = time.Second
+	//DefaultEntryTimeout is the entries kernel cache timeout if not set in the options
+	DefaultEntryTimeout = time.Second
+)
+
 //G8ufs struct
 type G8ufs struct {
 	*rofs.Config
-	layers []string
-	w      sync.WaitGroup
+	layers   []string
+	manager  *rofs.CacheManager
+	recorder *rofs.Recorder
+	cancel   context.CancelFunc
+	w        sync.WaitGroup
+
+	//invalidate drops the entries and attributes cached by the kernel
+	invalidate func()
 }
 
-func mountRO(target string, storage storage.Storage, meta meta.Store, cache string) (*G8ufs, error) {
-	log.Debugf("ro: '%s' ca: %s", target, cache)
-
-	cfg := rofs.NewConfig(storage, meta, cache)
-	fs := rofs.New(cfg)
-	// opts := nodefs.Options{Debug: true}
-	opts := nodefs.Options{}
-
-	server, err := fuse.NewServer(
-		nodefs.NewFileSystemConnector(
-			pathfs.NewPathNodeFs(fs, nil).Root(),
-			&opts,
-		).RawFS(), target, &fuse.MountOptions{
-			// Debug:         true,
-			AllowOther:    true,
-			FsName:        "g8ufs",
-			DisableXAttrs: true,
-			Options:       []string{"ro", "default_permissions"},
+func mountRO(target string, cfg *rofs.Config, opt *Options) (*G8ufs, error) {
+	log.Debugf("ro: '%s'", target)
+
+	attrTimeout, entryTimeout := opt.AttrTimeout, opt.EntryTimeout
+	if attrTimeout == 0 {
+		attrTimeout = DefaultAttrTimeout
+	}
+
+	if entryTimeout == 0 {
+		entryTimeout = DefaultEntryTimeout
+	}
+
+	negativeTimeout := opt.NegativeTimeout
+
+	mountOpts := fuse.MountOptions{
+		// Debug:         true,
+		AllowOther:    true,
+		FsName:        "g8ufs",
+		Options:       []string{"ro", "default_permissions"},
+		MaxReadAhead:  opt.MaxReadAhead,
+		MaxBackground: opt.MaxBackground,
+	}
+
+	var raw fuse.RawFileSystem
+	var invalidate func()
+	if opt.InodeFS {
+		root, err := rofs.NewRoot(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		raw = fusefs.NewNodeFS(root, &fusefs.Options{
+			MountOptions:    mountOpts,
+			EntryTimeout:    &entryTimeout,
+			AttrTimeout:     &attrTimeout,
+			NegativeTimeout: &negativeTimeout,
 		})
 
+		invalidate = func() {
+			invalidateInode(root.EmbeddedInode())
+		}
+	} else {
+		fs := rofs.New(cfg)
+		// opts := nodefs.Options{Debug: true}
+		opts := nodefs.Options{
+			EntryTimeout:    entryTimeout,
+			AttrTimeout:     attrTimeout,
+			NegativeTimeout: negativeTimeout,
+		}
+
+		root := pathfs.NewPathNodeFs(fs, nil).Root()
+		conn := nodefs.NewFileSystemConnector(root, &opts)
+		raw = conn.RawFS()
+
+		invalidate = func() {
+			invalidatePath(conn, root.Inode())
+		}
+	}
+
+	server, err := fuse.NewServer(raw, target, &mountOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -85,8 +179,9 @@ func mountRO(target string, storage storage.Storage, meta meta.Store, cache stri
 	go server.Serve()
 
 	zfs := &G8ufs{
-		Config: cfg,
-		layers: []string{target},
+		Config:     cfg,
+		layers:     []string{target},
+		invalidate: invalidate,
 	}
 
 	log.Debugf("Waiting for fuse mount")
@@ -99,6 +194,18 @@ func mountRO(target string, storage storage.Storage, meta meta.Store, cache stri
 func Mount(opt *Options) (fs *G8ufs, err error) {
 	backend := opt.Backend
 
+	var trace []string
+	if len(opt.ReplayTrace) != 0 {
+		//the trace is read before the reset, since it can be in the backend
+		var terr error
+		trace, terr = readTrace(opt.ReplayTrace)
+		if os.IsNotExist(terr) {
+			log.Warningf("access trace '%s' does not exist, skipping replay", opt.ReplayTrace)
+		} else if terr != nil {
+			log.Errorf("failed to read access trace, skipping replay: %s", terr)
+		}
+	}
+
 	if opt.Reset {
 		os.RemoveAll(backend)
 	}
@@ -113,6 +220,17 @@ func Mount(opt *Options) (fs *G8ufs, err error) {
 		return
 	}
 
+	go func() {
+		//the cache files are only created on open, remove the ones created on stat by older versions.
+		//this only runs until a pass over the cache directory succeeded
+		removed, err := rofs.RemovePlaceholders(ca)
+		if err != nil {
+			log.Errorf("failed to clean up cache directory (%s): %s", ca, err)
+		} else if removed != 0 {
+			log.Infof("removed %d empty files from cache directory (%s)", removed, ca)
+		}
+	}()
+
 	ro := path.Join(backend, "ro") //ro lower layer provided by fuse
 	if opt.ReadOnly {
 		ro = opt.Target
@@ -123,13 +241,61 @@ func Mount(opt *Options) (fs *G8ufs, err error) {
 		return
 	}
 
-	fs, err = mountRO(ro, opt.Storage, opt.Store, ca)
+	var manager *rofs.CacheManager
+	if opt.CacheSize != 0 {
+		low, high := opt.CacheLowWatermark, opt.CacheHighWatermark
+		if low == 0 {
+			low = rofs.DefaultLowWatermark
+		}
+
+		if high == 0 {
+			high = rofs.DefaultHighWatermark
+		}
+
+		manager, err = rofs.NewCacheManager(ca, opt.CacheSize, rofs.WithWatermarks(low, high))
+		if err != nil {
+			err = fmt.Errorf("failed to create cache manager: %s", err)
+			return
+		}
+
+		manager.Start()
+	}
+
+	var recorder *rofs.Recorder
+	if opt.RecordTrace {
+		if err = os.MkdirAll(backend, 0755); err != nil {
+			err = fmt.Errorf("failed to create backend directory '%s': %s", backend, err)
+			return
+		}
+
+		recorder, err = rofs.NewRecorder(path.Join(backend, TraceFile))
+		if err != nil {
+			err = fmt.Errorf("failed to create access trace: %s", err)
+			return
+		}
+	}
+
+	cfg := rofs.NewConfig(opt.Storage, opt.Store, ca)
+	cfg.SetCacheManager(manager)
+	cfg.SetRecorder(recorder)
+	cfg.SetVerify(opt.VerifyCache)
+	cfg.SetBlockCache(opt.BlockCache)
+
+	fs, err = mountRO(ro, cfg, opt)
 	if err != nil {
+		if manager != nil {
+			manager.Stop()
+		}
+		if recorder != nil {
+			recorder.Close()
+		}
 		err = fmt.Errorf("failed to do ro layer mount: %s", err)
 		return
 	}
 
 	log.Debugf("read-only layer mounted")
+	fs.manager = manager
+	fs.recorder = recorder
 
 	defer func() {
 		if err != nil {
@@ -139,6 +305,15 @@ func Mount(opt *Options) (fs *G8ufs, err error) {
 
 		fs.w.Add(1)
 		go fs.watch()
+
+		if len(trace) != 0 {
+			var opts []rofs.PrefetchOption
+			if opt.BlockCache {
+				opts = append(opts, rofs.WithBlockCache())
+			}
+
+			fs.replay(opt.Storage, opt.Store, ca, trace, opts...)
+		}
 	}()
 
 	if opt.ReadOnly {
@@ -204,6 +379,68 @@ func Mount(opt *Options) (fs *G8ufs, err error) {
 	return fs, nil
 }
 
+//SetMetaStore changes the meta store of the read-only layer, the entries and
+//attributes cached by the kernel are invalidated
+func (fs *G8ufs) SetMetaStore(store meta.Store) {
+	fs.Config.SetMetaStore(store)
+	if fs.invalidate != nil {
+		fs.invalidate()
+	}
+}
+
+//invalidatePath invalidates the kernel cache of node and all its known children
+func invalidatePath(conn *nodefs.FileSystemConnector, node *nodefs.Inode) {
+	for name, child := range node.Children() {
+		invalidatePath(conn, child)
+		if status := conn.EntryNotify(node, name); !status.Ok() && status != fuse.ENOENT {
+			log.Debugf("failed to invalidate entry '%s': %s", name, status)
+		}
+	}
+
+	conn.FileNotify(node, 0, 0)
+}
+
+//invalidateInode invalidates the kernel cache of inode and all its known children
+func invalidateInode(inode *fusefs.Inode) {
+	for name, child := range inode.Children() {
+		invalidateInode(child)
+		if errno := inode.NotifyEntry(name); errno != 0 && errno != syscall.ENOENT {
+			log.Debugf("failed to invalidate entry '%s': %s", name, errno)
+		}
+	}
+
+	inode.NotifyContent(0, 0)
+}
+
+func readTrace(name string) ([]string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return rofs.ReadAccessList(file)
+}
+
+//replay downloads the files of the trace in the background
+func (fs *G8ufs) replay(storage storage.Storage, store meta.Store, cache string, trace []string, opts ...rofs.PrefetchOption) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fs.cancel = cancel
+
+	go func() {
+		log.Infof("replaying access trace of %d entries", len(trace))
+		prefetcher := rofs.NewPrefetcher(storage, store, cache, opts...)
+		if err := prefetcher.Paths(ctx, trace); err == context.Canceled {
+			log.Info("access trace replay canceled")
+		} else if err != nil {
+			log.Errorf("failed to replay access trace: %s", err)
+		} else {
+			log.Info("access trace replay done")
+		}
+	}()
+}
+
 func (fs *G8ufs) watch() {
 	defer fs.w.Done()
 
@@ -247,6 +484,18 @@ func (e errors) Error() string {
 func (fs *G8ufs) Unmount() error {
 	var errs errors
 
+	if fs.cancel != nil {
+		fs.cancel()
+	}
+
+	if fs.manager != nil {
+		fs.manager.Stop()
+	}
+
+	if fs.recorder != nil {
+		fs.recorder.Close()
+	}
+
 	for i := len(fs.layers) - 1; i >= 0; i-- {
 		if err := syscall.Unmount(fs.layers[i], syscall.MNT_FORCE|syscall.MNT_DETACH); err != nil {
 			errs = append(errs, err)
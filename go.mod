@@ -19,10 +19,11 @@ require (
 	github.com/philhofer/fwd v1.0.0 // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/prometheus/client_golang v1.7.1
 	github.com/remyoudompheng/go-misc v0.0.0-20190427085024-2d6ac652a50e // indirect
 	github.com/sevlyar/go-daemon v0.1.5
 	github.com/stretchr/objx v0.1.1 // indirect
-	github.com/stretchr/testify v1.2.2
+	github.com/stretchr/testify v1.4.0
 	github.com/tinylib/msgp v1.1.2 // indirect
 	github.com/xxtea/xxtea-go v0.0.0-20170828040851-35c4b17eecf6
 	golang.org/x/crypto v0.0.0-20200728195943-123391ffb6de
@@ -31,7 +32,6 @@ require (
 	golang.org/x/sync v0.0.0-20200625203802-6e8e738ad208
 	golang.org/x/sys v0.0.0-20200802091954-4b90ce9b60b3
 	golang.org/x/tools v0.0.0-20200823205832-c024452afbcd // indirect
-	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 	gopkg.in/yaml.v2 v2.3.0
 	honnef.co/go/tools v0.0.1-2020.1.5 // indirect
 	zombiezen.com/go/capnproto2 v2.18.0+incompatible
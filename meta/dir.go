@@ -54,12 +54,18 @@ func (d *Dir) Info() Info {
 			Size:             4096,
 			Type:             DirType,
 			Access:           d.access,
+			XAttrs:           getXAttrs(d.Xattrs()),
 		}
 	})
 
 	return d.info
 }
 
+//XAttrs returns the dir extended attributes
+func (d *Dir) XAttrs() map[string][]byte {
+	return d.Info().XAttrs
+}
+
 //Children return items in this dir
 func (d *Dir) Children() []Meta {
 	d.cOnce.Do(func() {
@@ -90,7 +96,11 @@ func (d *Dir) getChildren() []Meta {
 		case np.Inode_attributes_Which_dir:
 			dir, _ := attributes.Dir()
 			subkey, _ := dir.Key()
-			sub, _ := d.store.getDirWithHash(subkey)
+			sub, err := d.store.getDirWithHash(subkey)
+			if err != nil {
+				log.Errorf("unable to read directory %s: %s", subkey, err)
+				continue
+			}
 			m = sub
 		case np.Inode_attributes_Which_file:
 			file, _ := attributes.File()
@@ -103,6 +113,10 @@ func (d *Dir) getChildren() []Meta {
 			access, _ := d.store.getAccess(key)
 			m = &Link{Inode: inode, link: link, access: access}
 		case np.Inode_attributes_Which_special:
+			special, _ := attributes.Special()
+			key, _ := inode.Aclkey()
+			access, _ := d.store.getAccess(key)
+			m = &Special{Inode: inode, special: special, access: access}
 		default:
 			continue
 		}
@@ -61,12 +61,20 @@ func (f *File) Info() Info {
 			Type:             RegularType,
 			Access:           f.access,
 			FileBlockSize:    uint64(f.file.BlockSize()) * 4096,
+			XAttrs:           getXAttrs(f.Xattrs()),
+			InodeID:          f.InodeId(),
+			NLink:            f.Nlink(),
 		}
 	})
 
 	return f.info
 }
 
+//XAttrs returns the file extended attributes
+func (f *File) XAttrs() map[string][]byte {
+	return f.Info().XAttrs
+}
+
 func (f *File) getBlocks() []BlockInfo {
 	var blocks []BlockInfo
 	if !f.file.HasBlocks() {
@@ -1,6 +1,10 @@
 package meta
 
-import "sync"
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+)
 
 type stores []Store
 
@@ -71,6 +75,55 @@ func (s stores) Get(p string) (Meta, bool) {
 	return nil, false
 }
 
+//Walk implements Walker, directories are merged over all layers
+func (s stores) Walk(root string, fn WalkFn) error {
+	m, ok := s.Get(root)
+	if !ok {
+		return fmt.Errorf("%s not found", root)
+	}
+
+	if m.IsDir() {
+		return s.walkDir(root, m, fn)
+	}
+
+	return fn(root, m)
+}
+
+func (s stores) walkDir(root string, dir Meta, fn WalkFn) error {
+	err := fn(root, dir)
+	if err == ErrSkipDir {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, child := range dir.Children() {
+		path := filepath.Join(root, child.Name())
+		if child.IsDir() {
+			//get the merged directory
+			merged, ok := s.Get(path)
+			if !ok {
+				continue
+			}
+
+			if err := s.walkDir(path, merged, fn); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		err := fn(path, child)
+		if err == ErrSkipDir {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s stores) Close() error {
 	// TODO: aggregate all the errors
 	for _, store := range s {
@@ -43,6 +43,11 @@ func (l *Link) Blocks() []BlockInfo {
 	return nil
 }
 
+//XAttrs returns the link extended attributes
+func (l *Link) XAttrs() map[string][]byte {
+	return l.Info().XAttrs
+}
+
 //Children returns empty list
 func (l *Link) Children() []Meta {
 	return nil
@@ -59,6 +64,9 @@ func (l *Link) Info() Info {
 			Type:             LinkType,
 			Access:           l.access,
 			LinkTarget:       target,
+			XAttrs:           getXAttrs(l.Xattrs()),
+			InodeID:          l.InodeId(),
+			NLink:            l.Nlink(),
 		}
 	})
 
@@ -1,11 +1,15 @@
 package meta
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"syscall"
 
 	"github.com/op/go-logging"
+	np "github.com/threefoldtech/0-fs/cap.np"
+	"golang.org/x/crypto/blake2b"
 )
 
 var (
@@ -78,6 +82,28 @@ type Info struct {
 
 	//Special
 	SpecialData string
+
+	//Extended attributes (ex: security.capability)
+	XAttrs map[string][]byte
+
+	//Hard links, all the links of the same file have the same InodeID (0 if
+	//the file has a single link), NLink is the number of links
+	InodeID uint64
+	NLink   uint32
+}
+
+//InodeNumber returns a stable inode number for the entry at path
+func InodeNumber(path string) uint64 {
+	hasher, _ := blake2b.New(8, nil)
+	io.WriteString(hasher, path)
+
+	ino := binary.BigEndian.Uint64(hasher.Sum(nil))
+	if ino == 0 {
+		//0 means no inode number
+		ino = 1
+	}
+
+	return ino
 }
 
 // BlockInfo is the information needed to retrieve and decrypt a data block
@@ -94,12 +120,31 @@ type Meta interface {
 	Name() string
 	IsDir() bool
 	Blocks() []BlockInfo
+	//XAttrs returns the extended attributes of the entry, nil if it has none
+	XAttrs() map[string][]byte
 
 	Info() Info
 
 	Children() []Meta
 }
 
+//getXAttrs loads a capnp list of extended attributes
+func getXAttrs(list np.XAttr_List, err error) map[string][]byte {
+	if err != nil || list.Len() == 0 {
+		return nil
+	}
+
+	xattrs := make(map[string][]byte, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		xattr := list.At(i)
+		name, _ := xattr.Name()
+		value, _ := xattr.Value()
+		xattrs[name] = value
+	}
+
+	return xattrs
+}
+
 // WalkFn walk function
 type WalkFn func(path string, meta Meta) error
 
@@ -43,6 +43,11 @@ func (s *Special) Blocks() []BlockInfo {
 	return nil
 }
 
+//XAttrs returns the special file extended attributes
+func (s *Special) XAttrs() map[string][]byte {
+	return s.Info().XAttrs
+}
+
 //Children returns empty list
 func (s *Special) Children() []Meta {
 	return nil
@@ -78,5 +83,8 @@ func (s *Special) getInfo() Info {
 		Type:             t,
 		Access:           s.access,
 		SpecialData:      string(data),
+		XAttrs:           getXAttrs(s.Xattrs()),
+		InodeID:          s.InodeId(),
+		NLink:            s.Nlink(),
 	}
 }
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"os/user"
 	"path"
 	"path/filepath"
@@ -99,6 +98,12 @@ func (s *sqlStore) Close() error {
 }
 
 func (s *sqlStore) hash(path string) string {
+	return Hash(path)
+}
+
+//Hash returns the key of the directory entry with the given path as stored
+//in the flist database
+func Hash(path string) string {
 	hasher, _ := blake2b.New(16, nil)
 	io.WriteString(hasher, path)
 
@@ -195,7 +200,7 @@ func (s *sqlStore) getAccess(key string) (Access, error) {
 
 	mode := uint32(aci.Mode())
 	return Access{
-		Mode: uint32(os.ModePerm) & mode,
+		Mode: 07777 & mode, //permissions with the setuid, setgid and sticky bits
 		UID:  uint32(uid),
 		GID:  uint32(gid),
 	}, nil
@@ -0,0 +1,716 @@
+package writer
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/golang/snappy"
+	"github.com/op/go-logging"
+	// import sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+	np "github.com/threefoldtech/0-fs/cap.np"
+	"github.com/threefoldtech/0-fs/meta"
+	"github.com/threefoldtech/0-fs/storage"
+	"github.com/xxtea/xxtea-go/xxtea"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/sys/unix"
+	capnp "zombiezen.com/go/capnproto2"
+)
+
+const (
+	//DefaultBlockSize is the default block size
+	DefaultBlockSize = 512 //KB
+	//MaxBlockSize is the max block size an flist can describe
+	MaxBlockSize = 128 * 1024 //KB
+
+	pageSize = 4 * 1024
+)
+
+var (
+	log = logging.MustGetLogger("writer")
+)
+
+// Writer builds an flist database out of a local directory tree and uploads
+// the files blocks to a storage
+type Writer struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	stmt    *sql.Stmt
+	storage storage.Writer
+
+	blockSize uint64
+
+	acis   map[string]struct{}
+	users  map[uint32]string
+	groups map[uint32]string
+	links  map[devIno]*hardlink
+}
+
+type devIno struct {
+	dev uint64
+	ino uint64
+}
+
+// hardlink is a file with multiple links in the tree being added
+type hardlink struct {
+	id     uint64
+	nlink  uint32
+	blocks []meta.BlockInfo
+	done   bool
+}
+
+// Option interface
+type Option interface {
+	apply(w *Writer)
+}
+
+type blockSizeOpt struct {
+	size uint64
+}
+
+func (o blockSizeOpt) apply(w *Writer) {
+	w.blockSize = o.size
+}
+
+// WithBlockSize sets the size (in KB) of the blocks files are split into
+func WithBlockSize(kb uint) Option {
+	return blockSizeOpt{uint64(kb) * 1024}
+}
+
+// New creates a new writer that creates the flist database under directory p
+// and pushes the files blocks to storage
+func New(p string, storage storage.Writer, opts ...Option) (*Writer, error) {
+	w := &Writer{
+		storage:   storage,
+		blockSize: DefaultBlockSize * 1024,
+		acis:      make(map[string]struct{}),
+		users:     make(map[uint32]string),
+		groups:    make(map[uint32]string),
+		links:     make(map[devIno]*hardlink),
+	}
+
+	for _, opt := range opts {
+		opt.apply(w)
+	}
+
+	if w.blockSize == 0 || w.blockSize%pageSize != 0 || w.blockSize > MaxBlockSize*1024 {
+		return nil, fmt.Errorf("invalid block size %d, must be a multiple of 4 KB up to %d KB", w.blockSize/1024, MaxBlockSize)
+	}
+
+	if err := os.MkdirAll(p, 0755); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", path.Join(p, meta.SQLiteDBName))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec("create table if not exists entries (key varchar(64) primary key, value blob)"); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("insert or replace into entries (key, value) values (?, ?)")
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, err
+	}
+
+	w.db = db
+	w.tx = tx
+	w.stmt = stmt
+
+	return w, nil
+}
+
+// Add walks the directory tree under root and adds all its entries to the flist.
+// root itself becomes the root of the flist
+func (w *Writer) Add(root string) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("'%s' is not a directory", root)
+	}
+
+	if err := w.hardlinks(root); err != nil {
+		return err
+	}
+
+	return w.dir(root, "", info)
+}
+
+// hardlinks finds the files that have more than one link under root. All the links
+// of a file get the inode number of the first link as their shared inode id
+func (w *Writer) hardlinks(root string) error {
+	return filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if info.IsDir() || !ok || stat.Nlink < 2 {
+			return nil
+		}
+
+		key := devIno{dev: uint64(stat.Dev), ino: stat.Ino}
+		if link, ok := w.links[key]; ok {
+			link.nlink++
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+
+		w.links[key] = &hardlink{id: meta.InodeNumber(rel), nlink: 1}
+		return nil
+	})
+}
+
+// hardlink returns the hard link info of an entry, or nil if it has no other
+// links in the tree
+func (w *Writer) hardlink(info os.FileInfo) *hardlink {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Nlink < 2 {
+		return nil
+	}
+
+	link, ok := w.links[devIno{dev: uint64(stat.Dev), ino: stat.Ino}]
+	if !ok || link.nlink < 2 {
+		//other links are outside of the tree
+		return nil
+	}
+
+	return link
+}
+
+// Close commits the flist database
+func (w *Writer) Close() error {
+	w.stmt.Close()
+	if err := w.tx.Commit(); err != nil {
+		w.db.Close()
+		return err
+	}
+
+	return w.db.Close()
+}
+
+// Discard drops the entries added so far and closes the flist database
+func (w *Writer) Discard() error {
+	w.stmt.Close()
+	if err := w.tx.Rollback(); err != nil {
+		w.db.Close()
+		return err
+	}
+
+	return w.db.Close()
+}
+
+func (w *Writer) set(key string, msg *capnp.Message) error {
+	var buf bytes.Buffer
+	if err := capnp.NewEncoder(&buf).Encode(msg); err != nil {
+		return err
+	}
+
+	_, err := w.stmt.Exec(key, buf.Bytes())
+	return err
+}
+
+// dir writes the directory entry at rel (relative to root) and recursively
+// all its children
+func (w *Writer) dir(root, rel string, info os.FileInfo) error {
+	log.Debugf("adding directory '/%s'", rel)
+	entries, err := ioutil.ReadDir(filepath.Join(root, rel))
+	if err != nil {
+		return err
+	}
+
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return err
+	}
+
+	dir, err := np.NewRootDir(seg)
+	if err != nil {
+		return err
+	}
+
+	contents, err := dir.NewContents(int32(len(entries)))
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+		if err := w.inode(root, path.Join(rel, entry.Name()), entry, contents.At(i)); err != nil {
+			return err
+		}
+	}
+
+	aclkey, err := w.aci(info)
+	if err != nil {
+		return err
+	}
+
+	mtime, ctime := times(info)
+
+	name := path.Base(rel)
+	if rel == "" {
+		name = ""
+	} else {
+		parent := path.Dir(rel)
+		if parent == "." {
+			parent = ""
+		}
+
+		if err := dir.SetParent(meta.Hash(parent)); err != nil {
+			return err
+		}
+	}
+
+	if err := dir.SetName(name); err != nil {
+		return err
+	}
+
+	if err := dir.SetLocation(rel); err != nil {
+		return err
+	}
+
+	if err := dir.SetAclkey(aclkey); err != nil {
+		return err
+	}
+
+	dir.SetSize(uint64(info.Size()))
+	dir.SetModificationTime(mtime)
+	dir.SetCreationTime(ctime)
+
+	if err := setXAttrs(filepath.Join(root, rel), dir.NewXattrs); err != nil {
+		return err
+	}
+
+	return w.set(meta.Hash(rel), msg)
+}
+
+// inode fills the inode entry of the directory child at rel
+func (w *Writer) inode(root, rel string, info os.FileInfo, inode np.Inode) error {
+	if err := inode.SetName(info.Name()); err != nil {
+		return err
+	}
+
+	aclkey, err := w.aci(info)
+	if err != nil {
+		return err
+	}
+
+	if err := inode.SetAclkey(aclkey); err != nil {
+		return err
+	}
+
+	mtime, ctime := times(info)
+	inode.SetModificationTime(mtime)
+	inode.SetCreationTime(ctime)
+	inode.SetSize(uint64(info.Size()))
+
+	link := w.hardlink(info)
+	if link != nil {
+		inode.SetInodeId(link.id)
+		inode.SetNlink(link.nlink)
+	}
+
+	mode := info.Mode()
+	if !mode.IsDir() {
+		//directories keep their extended attributes in their own entry
+		if err := setXAttrs(filepath.Join(root, rel), inode.NewXattrs); err != nil {
+			return err
+		}
+	}
+
+	attributes := inode.Attributes()
+	switch {
+	case mode.IsDir():
+		if err := w.dir(root, rel, info); err != nil {
+			return err
+		}
+
+		sub, err := attributes.NewDir()
+		if err != nil {
+			return err
+		}
+
+		return sub.SetKey(meta.Hash(rel))
+	case mode.IsRegular():
+		file, err := attributes.NewFile()
+		if err != nil {
+			return err
+		}
+
+		if link == nil {
+			return w.file(filepath.Join(root, rel), file)
+		}
+
+		if !link.done {
+			//first link, the blocks are uploaded once for all links
+			blocks, err := w.upload(filepath.Join(root, rel))
+			if err != nil {
+				return err
+			}
+
+			link.blocks = blocks
+			link.done = true
+		}
+
+		return w.setBlocks(file, link.blocks)
+	case mode&os.ModeSymlink != 0:
+		target, err := os.Readlink(filepath.Join(root, rel))
+		if err != nil {
+			return err
+		}
+
+		link, err := attributes.NewLink()
+		if err != nil {
+			return err
+		}
+
+		return link.SetTarget(target)
+	default:
+		special, err := attributes.NewSpecial()
+		if err != nil {
+			return err
+		}
+
+		return w.special(info, special)
+	}
+}
+
+// file splits the file into blocks and uploads them
+func (w *Writer) file(name string, file np.File) error {
+	blocks, err := w.upload(name)
+	if err != nil {
+		return err
+	}
+
+	return w.setBlocks(file, blocks)
+}
+
+// upload splits the file into blocks and uploads them
+func (w *Writer) upload(name string) ([]meta.BlockInfo, error) {
+	log.Debugf("adding file '%s'", name)
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var blocks []meta.BlockInfo
+	buf := make([]byte, w.blockSize)
+	for {
+		n, err := io.ReadFull(f, buf)
+		if err == io.EOF {
+			break
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+
+		block, data, err := encodeBlock(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+
+		if err := w.storage.Put(block.Key, data); err != nil {
+			return nil, fmt.Errorf("failed to upload block %d of '%s': %s", len(blocks), name, err)
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
+// setBlocks sets the blocks of a file entry
+func (w *Writer) setBlocks(file np.File, blocks []meta.BlockInfo) error {
+	file.SetBlockSize(uint16(w.blockSize / pageSize))
+	list, err := file.NewBlocks(int32(len(blocks)))
+	if err != nil {
+		return err
+	}
+
+	for i, block := range blocks {
+		entry := list.At(i)
+		if err := entry.SetHash(block.Key); err != nil {
+			return err
+		}
+
+		if err := entry.SetKey(block.Decipher); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) special(info os.FileInfo, special np.Special) error {
+	mode := info.Mode()
+	switch {
+	case mode&os.ModeSocket != 0:
+		special.SetType(np.Special_Type_socket)
+	case mode&os.ModeNamedPipe != 0:
+		special.SetType(np.Special_Type_fifopipe)
+	case mode&os.ModeCharDevice != 0:
+		special.SetType(np.Special_Type_chardev)
+	case mode&os.ModeDevice != 0:
+		special.SetType(np.Special_Type_block)
+	default:
+		special.SetType(np.Special_Type_unknown)
+	}
+
+	if mode&os.ModeDevice == 0 {
+		return nil
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	dev := uint64(stat.Rdev)
+	return special.SetData([]byte(fmt.Sprintf("%d,%d", unix.Major(dev), unix.Minor(dev))))
+}
+
+// unixMode returns the permission bits of mode, including the setuid, setgid and
+// sticky bits, in the unix format stored in the access object
+func unixMode(mode os.FileMode) uint16 {
+	bits := uint16(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= unix.S_ISUID
+	}
+
+	if mode&os.ModeSetgid != 0 {
+		bits |= unix.S_ISGID
+	}
+
+	if mode&os.ModeSticky != 0 {
+		bits |= unix.S_ISVTX
+	}
+
+	return bits
+}
+
+// aci makes sure the access object of this entry is stored and returns its key
+func (w *Writer) aci(info os.FileInfo) (string, error) {
+	var uid, gid uint32
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		uid, gid = stat.Uid, stat.Gid
+	}
+
+	mode := unixMode(info.Mode())
+	key := meta.Hash(fmt.Sprintf("%d:%d:%o", uid, gid, mode))
+	if _, ok := w.acis[key]; ok {
+		return key, nil
+	}
+
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return key, err
+	}
+
+	aci, err := np.NewRootACI(seg)
+	if err != nil {
+		return key, err
+	}
+
+	aci.SetUid(int64(uid))
+	aci.SetGid(int64(gid))
+	aci.SetMode(mode)
+	if err := aci.SetUname(w.lookUpUser(uid)); err != nil {
+		return key, err
+	}
+
+	if err := aci.SetGname(w.lookUpGroup(gid)); err != nil {
+		return key, err
+	}
+
+	if err := w.set(key, msg); err != nil {
+		return key, err
+	}
+
+	w.acis[key] = struct{}{}
+	return key, nil
+}
+
+func (w *Writer) lookUpUser(uid uint32) string {
+	if name, ok := w.users[uid]; ok {
+		return name
+	}
+
+	name := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+
+	w.users[uid] = name
+	return name
+}
+
+func (w *Writer) lookUpGroup(gid uint32) string {
+	if name, ok := w.groups[gid]; ok {
+		return name
+	}
+
+	name := strconv.FormatUint(uint64(gid), 10)
+	if g, err := user.LookupGroupId(name); err == nil {
+		name = g.Name
+	}
+
+	w.groups[gid] = name
+	return name
+}
+
+// readXAttrs reads the extended attributes of name, without following symlinks
+func readXAttrs(name string) (map[string][]byte, error) {
+	buf := make([]byte, 1024)
+	for {
+		n, err := unix.Llistxattr(name, buf)
+		if err == unix.ENOTSUP {
+			//the filesystem has no extended attributes
+			return nil, nil
+		} else if err == unix.ERANGE {
+			buf = make([]byte, len(buf)*2)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		buf = buf[:n]
+		break
+	}
+
+	xattrs := make(map[string][]byte)
+	for _, attr := range strings.Split(string(buf), "\x00") {
+		if len(attr) == 0 {
+			continue
+		}
+
+		value := make([]byte, 256)
+		for {
+			n, err := unix.Lgetxattr(name, attr, value)
+			if err == unix.ENODATA {
+				//removed while reading
+				value = nil
+				break
+			} else if err == unix.ERANGE {
+				value = make([]byte, len(value)*2)
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			value = value[:n]
+			break
+		}
+
+		if value != nil {
+			xattrs[attr] = value
+		}
+	}
+
+	return xattrs, nil
+}
+
+// setXAttrs stores the extended attributes of name in the list created by newList
+func setXAttrs(name string, newList func(n int32) (np.XAttr_List, error)) error {
+	xattrs, err := readXAttrs(name)
+	if err != nil {
+		return fmt.Errorf("failed to read extended attributes of '%s': %s", name, err)
+	}
+
+	if len(xattrs) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(xattrs))
+	for attr := range xattrs {
+		names = append(names, attr)
+	}
+
+	sort.Strings(names)
+	list, err := newList(int32(len(names)))
+	if err != nil {
+		return err
+	}
+
+	for i, attr := range names {
+		entry := list.At(i)
+		if err := entry.SetName(attr); err != nil {
+			return err
+		}
+
+		if err := entry.SetValue(xattrs[attr]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func times(info os.FileInfo) (mtime uint32, ctime uint32) {
+	mtime = uint32(info.ModTime().Unix())
+	ctime = mtime
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		ctime = uint32(stat.Ctim.Sec)
+	}
+
+	return
+}
+
+// encodeBlock compresses and encrypts a data block the way the rofs downloader
+// expects it. It returns the block info and the payload to store under the block key
+func encodeBlock(data []byte) (meta.BlockInfo, []byte, error) {
+	decipher, err := hash(data)
+	if err != nil {
+		return meta.BlockInfo{}, nil, err
+	}
+
+	payload := xxtea.Encrypt(snappy.Encode(nil, data), decipher)
+	key, err := hash(payload)
+	if err != nil {
+		return meta.BlockInfo{}, nil, err
+	}
+
+	return meta.BlockInfo{Key: key, Decipher: decipher}, payload, nil
+}
+
+func hash(data []byte) ([]byte, error) {
+	hasher, err := blake2b.New(16, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := hasher.Write(data); err != nil {
+		return nil, err
+	}
+
+	return hasher.Sum(nil), nil
+}
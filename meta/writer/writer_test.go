@@ -0,0 +1,309 @@
+package writer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/0-fs/meta"
+	"github.com/threefoldtech/0-fs/rofs"
+	"golang.org/x/sys/unix"
+)
+
+type TestStorage struct {
+	data map[string][]byte
+}
+
+func (t *TestStorage) Get(key []byte) (io.ReadCloser, error) {
+	if data, ok := t.data[string(key)]; ok {
+		return ioutil.NopCloser(bytes.NewBuffer(data)), nil
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+func (t *TestStorage) Put(key, data []byte) error {
+	t.data[string(key)] = data
+	return nil
+}
+
+func makeTree(t *testing.T, root string) []byte {
+	content := make([]byte, 10*1024+100)
+	rand.Read(content)
+
+	require.NoError(t, os.MkdirAll(path.Join(root, "sub", "deep"), 0755))
+	require.NoError(t, ioutil.WriteFile(path.Join(root, "sub", "deep", "data"), content, 0640))
+	require.NoError(t, ioutil.WriteFile(path.Join(root, "empty"), nil, 0600))
+	require.NoError(t, os.Symlink("sub/deep/data", path.Join(root, "link")))
+
+	return content
+}
+
+func TestWriter(t *testing.T) {
+	src, err := ioutil.TempDir("", "writer-src-")
+	require.NoError(t, err)
+	defer os.RemoveAll(src)
+
+	dst, err := ioutil.TempDir("", "writer-dst-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	content := makeTree(t, src)
+	storage := &TestStorage{data: make(map[string][]byte)}
+
+	w, err := New(dst, storage, WithBlockSize(4))
+	require.NoError(t, err)
+	require.NoError(t, w.Add(src))
+	require.NoError(t, w.Close())
+
+	// 10K + 100 bytes in 4K blocks
+	assert.Len(t, storage.data, 3)
+
+	store, err := meta.NewStore(dst)
+	require.NoError(t, err)
+	defer store.Close()
+
+	root, ok := store.Get("")
+	require.True(t, ok)
+	assert.True(t, root.IsDir())
+	assert.Len(t, root.Children(), 3)
+
+	empty, ok := store.Get("empty")
+	require.True(t, ok)
+	assert.Equal(t, meta.RegularType, empty.Info().Type)
+	assert.EqualValues(t, 0, empty.Info().Size)
+	assert.EqualValues(t, 0600, empty.Info().Access.Mode)
+	assert.Len(t, empty.Blocks(), 0)
+
+	link, ok := store.Get("link")
+	require.True(t, ok)
+	assert.Equal(t, meta.LinkType, link.Info().Type)
+	assert.Equal(t, "sub/deep/data", link.Info().LinkTarget)
+
+	sub, ok := store.Get("sub/deep")
+	require.True(t, ok)
+	assert.True(t, sub.IsDir())
+	assert.Equal(t, "deep", sub.Name())
+
+	file, ok := store.Get("sub/deep/data")
+	require.True(t, ok)
+	info := file.Info()
+	assert.Equal(t, meta.RegularType, info.Type)
+	assert.EqualValues(t, len(content), info.Size)
+	assert.EqualValues(t, 4096, info.FileBlockSize)
+	assert.EqualValues(t, 0640, info.Access.Mode)
+	assert.Len(t, file.Blocks(), 3)
+
+	out, err := ioutil.TempFile("", "writer-out-")
+	require.NoError(t, err)
+	defer func() {
+		out.Close()
+		os.Remove(out.Name())
+	}()
+
+	require.NoError(t, rofs.NewDownloader(storage, file).Download(out))
+	out.Seek(0, io.SeekStart)
+	downloaded, err := ioutil.ReadAll(out)
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+}
+
+func TestWriterSpecialModes(t *testing.T) {
+	src, err := ioutil.TempDir("", "writer-src-")
+	require.NoError(t, err)
+	defer os.RemoveAll(src)
+
+	dst, err := ioutil.TempDir("", "writer-dst-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	modes := map[string]os.FileMode{
+		"passwd": 0755 | os.ModeSetuid,
+		"wall":   0755 | os.ModeSetgid,
+	}
+
+	for name, mode := range modes {
+		require.NoError(t, ioutil.WriteFile(path.Join(src, name), nil, 0755))
+		require.NoError(t, os.Chmod(path.Join(src, name), mode))
+	}
+
+	require.NoError(t, os.Mkdir(path.Join(src, "tmp"), 0755))
+	require.NoError(t, os.Chmod(path.Join(src, "tmp"), 0777|os.ModeSticky))
+
+	w, err := New(dst, &TestStorage{data: make(map[string][]byte)})
+	require.NoError(t, err)
+	require.NoError(t, w.Add(src))
+	require.NoError(t, w.Close())
+
+	store, err := meta.NewStore(dst)
+	require.NoError(t, err)
+	defer store.Close()
+
+	for name, expected := range map[string]uint32{
+		"passwd": unix.S_ISUID | 0755,
+		"wall":   unix.S_ISGID | 0755,
+		"tmp":    unix.S_ISVTX | 0777,
+	} {
+		m, ok := store.Get(name)
+		require.True(t, ok, name)
+		assert.Equal(t, expected, m.Info().Access.Mode, name)
+	}
+}
+
+func TestWriterDiscard(t *testing.T) {
+	src, err := ioutil.TempDir("", "writer-src-")
+	require.NoError(t, err)
+	defer os.RemoveAll(src)
+
+	dst, err := ioutil.TempDir("", "writer-dst-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	makeTree(t, src)
+
+	w, err := New(dst, &TestStorage{data: make(map[string][]byte)})
+	require.NoError(t, err)
+	require.NoError(t, w.Add(src))
+	require.NoError(t, w.Discard())
+
+	store, err := meta.NewStore(dst)
+	require.NoError(t, err)
+	defer store.Close()
+
+	_, ok := store.Get("")
+	assert.False(t, ok)
+}
+
+func TestWriterInvalidBlockSize(t *testing.T) {
+	_, err := New(os.TempDir(), &TestStorage{}, WithBlockSize(3))
+	assert.Error(t, err)
+}
+
+func TestWriterXAttrs(t *testing.T) {
+	src, err := ioutil.TempDir("", "writer-src-")
+	require.NoError(t, err)
+	defer os.RemoveAll(src)
+
+	dst, err := ioutil.TempDir("", "writer-dst-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	makeTree(t, src)
+	err = unix.Setxattr(path.Join(src, "sub", "deep", "data"), "user.file", []byte("file value"), 0)
+	if err == unix.ENOTSUP {
+		t.Skip("extended attributes are not supported")
+	}
+
+	require.NoError(t, err)
+	require.NoError(t, unix.Setxattr(path.Join(src, "sub", "deep", "data"), "user.other", []byte{0, 1, 2}, 0))
+	require.NoError(t, unix.Setxattr(path.Join(src, "sub"), "user.dir", []byte("dir value"), 0))
+
+	w, err := New(dst, &TestStorage{data: make(map[string][]byte)})
+	require.NoError(t, err)
+	require.NoError(t, w.Add(src))
+	require.NoError(t, w.Close())
+
+	store, err := meta.NewStore(dst)
+	require.NoError(t, err)
+	defer store.Close()
+
+	file, ok := store.Get("sub/deep/data")
+	require.True(t, ok)
+	assert.Equal(t, map[string][]byte{
+		"user.file":  []byte("file value"),
+		"user.other": {0, 1, 2},
+	}, file.XAttrs())
+	assert.Equal(t, file.XAttrs(), file.Info().XAttrs)
+
+	sub, ok := store.Get("sub")
+	require.True(t, ok)
+	assert.Equal(t, map[string][]byte{"user.dir": []byte("dir value")}, sub.XAttrs())
+
+	empty, ok := store.Get("empty")
+	require.True(t, ok)
+	assert.Nil(t, empty.XAttrs())
+}
+
+func TestWriterHardlinks(t *testing.T) {
+	src, err := ioutil.TempDir("", "writer-src-")
+	require.NoError(t, err)
+	defer os.RemoveAll(src)
+
+	dst, err := ioutil.TempDir("", "writer-dst-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	content := makeTree(t, src)
+	require.NoError(t, os.Link(path.Join(src, "sub", "deep", "data"), path.Join(src, "data")))
+
+	storage := &TestStorage{data: make(map[string][]byte)}
+	w, err := New(dst, storage, WithBlockSize(4))
+	require.NoError(t, err)
+	require.NoError(t, w.Add(src))
+	require.NoError(t, w.Close())
+
+	// blocks of the linked file are uploaded once
+	assert.Len(t, storage.data, 3)
+
+	store, err := meta.NewStore(dst)
+	require.NoError(t, err)
+	defer store.Close()
+
+	first, ok := store.Get("data")
+	require.True(t, ok)
+	second, ok := store.Get("sub/deep/data")
+	require.True(t, ok)
+
+	assert.Equal(t, meta.InodeNumber("data"), first.Info().InodeID)
+	assert.Equal(t, first.Info().InodeID, second.Info().InodeID)
+	assert.EqualValues(t, 2, first.Info().NLink)
+	assert.EqualValues(t, 2, second.Info().NLink)
+	assert.EqualValues(t, len(content), second.Info().Size)
+	assert.Equal(t, first.Blocks(), second.Blocks())
+	assert.Equal(t, first.ID(), second.ID())
+
+	empty, ok := store.Get("empty")
+	require.True(t, ok)
+	assert.EqualValues(t, 0, empty.Info().InodeID)
+	assert.EqualValues(t, 0, empty.Info().NLink)
+}
+
+func TestPackUnpack(t *testing.T) {
+	src, err := ioutil.TempDir("", "writer-src-")
+	require.NoError(t, err)
+	defer os.RemoveAll(src)
+
+	dst, err := ioutil.TempDir("", "writer-dst-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	makeTree(t, src)
+	storage := &TestStorage{data: make(map[string][]byte)}
+
+	w, err := New(dst, storage)
+	require.NoError(t, err)
+	require.NoError(t, w.Add(src))
+	require.NoError(t, w.Close())
+
+	var archive bytes.Buffer
+	require.NoError(t, meta.Pack(dst, &archive))
+
+	unpacked, err := ioutil.TempDir("", "writer-unpack-")
+	require.NoError(t, err)
+	defer os.RemoveAll(unpacked)
+
+	require.NoError(t, meta.Unpack(&archive, unpacked))
+
+	store, err := meta.NewStore(unpacked)
+	require.NoError(t, err)
+	defer store.Close()
+
+	_, ok := store.Get("sub/deep/data")
+	assert.True(t, ok)
+}
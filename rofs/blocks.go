@@ -0,0 +1,249 @@
+package rofs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/threefoldtech/0-fs/meta"
+)
+
+const (
+	//blocksDir is the directory under the cache where blocks are stored
+	blocksDir = "blocks"
+)
+
+// blockID returns the id of a block in the block cache, blocks are identified by the
+// hash of their decrypted content so the same block is stored once
+func blockID(block meta.BlockInfo) string {
+	return fmt.Sprintf("%x", block.Decipher)
+}
+
+// blockPath returns the path of the block id in the block cache
+func (fs *filesystem) blockPath(id string) string {
+	base := filepath.Join(fs.cache, blocksDir)
+	if len(id) >= 4 {
+		base = filepath.Join(base, id[0:2], id[2:4])
+	}
+
+	return filepath.Join(base, id)
+}
+
+// blockDownloads makes sure a block (of the block cache or of a lazy cache file) is
+// downloaded once if it's read concurrently
+type blockDownloads struct {
+	inflight map[string]chan struct{}
+	m        sync.Mutex
+}
+
+// do runs fn for id, unless fn is already running for id in which case it waits
+// for it to finish and returns false
+func (b *blockDownloads) do(id string, fn func()) bool {
+	b.m.Lock()
+	if b.inflight == nil {
+		b.inflight = make(map[string]chan struct{})
+	}
+
+	if wait, ok := b.inflight[id]; ok {
+		b.m.Unlock()
+		<-wait
+		return false
+	}
+
+	done := make(chan struct{})
+	b.inflight[id] = done
+	b.m.Unlock()
+
+	fn()
+
+	b.m.Lock()
+	delete(b.inflight, id)
+	close(done)
+	b.m.Unlock()
+
+	return true
+}
+
+/*
+blockFile serves the content of a file directly from the block cache. Unlike the
+file cache, where each file has its own copy of its content, a block that is used
+by many files (or many flists) is downloaded and stored once.
+
+Blocks are written to the block cache atomically (write to a temporary file then
+rename), so multiple processes can share the same block cache.
+*/
+type blockFile struct {
+	nodefs.File
+
+	fs         *filesystem
+	downloader *Downloader
+	size       int64
+	touched    map[int]struct{}
+
+	m sync.Mutex
+}
+
+func newBlockFile(fs *filesystem, m meta.Meta) *blockFile {
+	return &blockFile{
+		File:       nodefs.NewDefaultFile(),
+		fs:         fs,
+		downloader: NewDownloader(fs.storage, m),
+		size:       int64(m.Info().Size),
+		touched:    make(map[int]struct{}),
+	}
+}
+
+// saveBlock writes a block to the block cache
+func (fs *filesystem) saveBlock(name string, data []byte) error {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0444); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// block opens block index from the block cache, the block is downloaded if needed
+func (f *blockFile) block(index int) (*os.File, error) {
+	block := f.downloader.blocks[index]
+	id := blockID(block)
+	name := f.fs.blockPath(id)
+
+	for {
+		file, err := os.Open(name)
+		if err == nil {
+			cacheBlockHits.Inc()
+			f.touch(index, name)
+			return file, nil
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		var derr error
+		downloaded := f.fs.blocks.do(id, func() {
+			var data []byte
+			data, derr = f.downloader.downloadBlock(block)
+			if derr != nil {
+				return
+			}
+
+			if derr = f.fs.saveBlock(name, data); derr != nil {
+				return
+			}
+
+			cacheBlockMisses.Inc()
+			cacheDownloadBytes.Add(float64(len(data)))
+			f.fs.manager.Add(uint64(len(data)))
+		})
+
+		if downloaded && derr != nil {
+			return nil, derr
+		}
+
+		file, err = os.Open(name)
+		if err == nil {
+			return file, nil
+		} else if !os.IsNotExist(err) || downloaded {
+			return nil, err
+		}
+
+		//the download of another reader failed, try again
+	}
+}
+
+// touch marks block index as recently used, once per open file
+func (f *blockFile) touch(index int, name string) {
+	f.m.Lock()
+	_, ok := f.touched[index]
+	f.touched[index] = struct{}{}
+	f.m.Unlock()
+
+	if !ok {
+		f.fs.manager.Touch(name)
+	}
+}
+
+func (f *blockFile) Read(dest []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	if off >= f.size || len(dest) == 0 {
+		return fuse.ReadResultData(nil), fuse.OK
+	}
+
+	end := off + int64(len(dest))
+	if end > f.size {
+		end = f.size
+	}
+
+	blockSize := int64(f.downloader.blockSize)
+	n := int64(0)
+	for pos := off; pos < end; {
+		index := pos / blockSize
+		if index >= int64(len(f.downloader.blocks)) {
+			break
+		}
+
+		file, err := f.block(int(index))
+		if err != nil {
+			log.Errorf("failed to get block %d: %s", index, err)
+			return nil, fuse.EIO
+		}
+
+		limit := (index + 1) * blockSize
+		if limit > end {
+			limit = end
+		}
+
+		want := limit - pos
+		read, err := file.ReadAt(dest[n:n+want], pos-index*blockSize)
+		file.Close()
+		if err != nil && err != io.EOF {
+			return nil, fuse.ToStatus(err)
+		}
+
+		n += int64(read)
+		pos += int64(read)
+		if int64(read) < want {
+			//short block
+			break
+		}
+	}
+
+	return fuse.ReadResultData(dest[:n]), fuse.OK
+}
+
+func (f *blockFile) Flush() fuse.Status {
+	return fuse.OK
+}
+
+func (f *blockFile) InnerFile() nodefs.File {
+	return nil
+}
+
+func (f *blockFile) String() string {
+	return "blockFile"
+}
@@ -0,0 +1,95 @@
+package rofs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/0-fs/meta/writer"
+)
+
+func TestBlockCache(t *testing.T) {
+	src, err := ioutil.TempDir("", "blocks-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	//b shares its first 2 blocks with a
+	a := make([]byte, 8*1024)
+	rand.Read(a)
+	b := make([]byte, 10*1024)
+	copy(b, a)
+	rand.Read(b[len(a):])
+
+	ioutil.WriteFile(filepath.Join(src, "a"), a, 0644)
+	ioutil.WriteFile(filepath.Join(src, "b"), b, 0644)
+
+	storage := putStorage{}
+	store, clean := writeFlist(t, src, storage, writer.WithBlockSize(4))
+	defer clean()
+
+	cache, err := ioutil.TempDir("", "blocks-cache-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(cache)
+
+	fs := &filesystem{Config: NewConfig(storage, store, cache)}
+	fs.SetBlockCache(true)
+
+	for name, expected := range map[string][]byte{"a": a, "b": b} {
+		m, _ := store.Get(name)
+		file, err := fs.open(m)
+		if ok := assert.NoError(t, err); !ok {
+			t.Fatal()
+		}
+
+		//read with a buffer that is not aligned to the blocks
+		var content bytes.Buffer
+		buf := make([]byte, 3000)
+		for off := int64(0); ; off += int64(len(buf)) {
+			result, status := file.Read(buf, off)
+			if ok := assert.True(t, status.Ok()); !ok {
+				t.Fatal()
+			}
+
+			data, _ := result.Bytes(nil)
+			if len(data) == 0 {
+				break
+			}
+
+			content.Write(data)
+		}
+
+		file.Release()
+		if ok := assert.Equal(t, expected, content.Bytes(), name); !ok {
+			t.Error()
+		}
+	}
+
+	//a has 2 blocks, b has the same 2 blocks and a third one
+	var blocks []string
+	filepath.Walk(cache, func(name string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			blocks = append(blocks, name)
+		}
+		return nil
+	})
+
+	if ok := assert.Len(t, blocks, 3); !ok {
+		t.Error()
+	}
+
+	for _, name := range blocks {
+		if ok := assert.Equal(t, filepath.Join(cache, blocksDir), filepath.Dir(filepath.Dir(filepath.Dir(name)))); !ok {
+			t.Error()
+		}
+	}
+}
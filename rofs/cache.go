@@ -1,12 +1,15 @@
 package rofs
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"syscall"
 
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
 	"github.com/threefoldtech/0-fs/meta"
 )
 
@@ -26,20 +29,6 @@ func (fs *filesystem) path(hash string) string {
 	return path.Join(base, hash)
 }
 
-// makes sure file exists in cache and return its stat
-func (fs *filesystem) check(m meta.Meta) (os.FileInfo, error) {
-	//atomic check and download a file
-	name := fs.path(m.ID())
-	f, err := fs.ensure(name)
-	if err != nil {
-		return nil, err
-	}
-
-	defer f.Close()
-
-	return f.Stat()
-}
-
 func (fs *filesystem) ensure(name string) (*os.File, error) {
 	for {
 		file, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR, 0444)
@@ -57,42 +46,274 @@ func (fs *filesystem) ensure(name string) (*os.File, error) {
 	}
 }
 
-// checkAndGet makes sure the file exists in cache and makes sure the file content is downloaded safely
-func (fs *filesystem) checkAndGet(m meta.Meta) (*os.File, error) {
-	//atomic check and download a file
+// cached checks if the cache file name (of size) holds the complete content of a file
+func cached(name string, size int64, info meta.Info) bool {
+	if size != int64(info.Size) {
+		return false
+	}
+
+	// a partially downloaded file has a blocks bitmap
+	_, err := os.Stat(bitmapPath(name))
+	return os.IsNotExist(err)
+}
+
+// placeholdersMarker is created in the cache root once the placeholders are removed
+const placeholdersMarker = ".placeholders-removed"
+
+// RemovePlaceholders removes the empty cache files left by older versions, which
+// created a cache file for every file that was stat'ed. Files in use are kept, and
+// it returns the number of removed files. It's a one time migration, a marker file
+// in root skips it once a pass succeeded
+func RemovePlaceholders(root string) (int, error) {
+	marker := filepath.Join(root, placeholdersMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return 0, nil
+	}
+
+	var removed int
+	err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			//removed while walking
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if info.IsDir() || info.Size() != 0 || strings.HasSuffix(name, bitmapSuffix) {
+			return nil
+		}
+
+		if _, ok := cacheEntryID(root, name); !ok {
+			return nil
+		}
+
+		// a partially downloaded file has a blocks bitmap
+		if _, err := os.Stat(bitmapPath(name)); err == nil {
+			return nil
+		}
+
+		ok, err := evict(name)
+		if err != nil {
+			return err
+		} else if ok {
+			removed++
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return removed, err
+	}
+
+	f, err := os.Create(marker)
+	if err != nil {
+		return removed, err
+	}
+
+	return removed, f.Close()
+}
+
+// linked checks that file was not removed (evicted) since it was opened
+func linked(file *os.File) (bool, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Fstat(int(file.Fd()), &stat); err != nil {
+		return false, err
+	}
+
+	return stat.Nlink != 0, nil
+}
+
+/*
+lock opens the cache file name with a shared lock. The lock is held as long as
+the file is open, it protects the file from eviction, which requires an exclusive
+lock on the cache file.
+
+Downloads are serialized with an exclusive lock on the blocks bitmap of the file
+(see fill and lazyFile).
+*/
+func (fs *filesystem) lock(name string) (*os.File, error) {
+	for {
+		f, err := fs.ensure(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH); err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		ok, err := linked(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		if ok {
+			return f, nil
+		}
+
+		// evicted while waiting for the lock
+		f.Close()
+	}
+}
+
+// open returns a file that serves the content of m. If the file is not in cache yet
+// only the blocks that are read are downloaded
+func (fs *filesystem) open(m meta.Meta) (nodefs.File, error) {
+	if fs.blockCache && m.Info().FileBlockSize != 0 {
+		return newBlockFile(fs, m), nil
+	}
+
 	name := fs.path(m.ID())
-	f, err := fs.ensure(name)
+	f, err := fs.lock(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.manager.Touch(name)
+
+	if fs.shouldVerify(m.ID()) {
+		corrupt, err := verify(f, m, true)
+		if err != nil {
+			log.Errorf("failed to verify cache file of '%s': %s", m.Name(), err)
+		} else {
+			if len(corrupt) != 0 {
+				log.Warningf("cache file of '%s' has %d corrupt blocks, downloading them again", m.Name(), len(corrupt))
+			}
+
+			fs.setVerified(m.ID())
+		}
+	}
+
+	fstat, err := f.Stat()
 	if err != nil {
+		f.Close()
 		return nil, err
 	}
-	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+
+	info := m.Info()
+	if cached(name, fstat.Size(), info) {
+		cacheHits.Inc()
+		return nodefs.NewLoopbackFile(f), nil
+	}
+
+	cacheMisses.Inc()
+	if info.FileBlockSize == 0 {
+		// can't compute block offsets, the full file has to be downloaded
+		if err := fs.fill(f, m); err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		return nodefs.NewLoopbackFile(f), nil
+	}
+
+	lazy, err := newLazyFile(f, NewDownloader(fs.storage, m), int64(info.Size))
+	if err != nil {
+		f.Close()
 		return nil, err
 	}
 
-	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	lazy.manager = fs.manager
+	lazy.downloads = &fs.blocks
+	return lazy, nil
+}
 
-	fstat, err := f.Stat()
+// checkAndGet makes sure the file exists in cache and makes sure the file content is downloaded safely.
+// The returned file holds a shared lock on the cache file until it's closed
+func (fs *filesystem) checkAndGet(m meta.Meta) (*os.File, error) {
+	name := fs.path(m.ID())
+	f, err := fs.lock(name)
+	if err != nil {
+		return nil, err
+	}
 
+	fstat, err := f.Stat()
 	if err != nil {
+		f.Close()
 		return nil, err
 	}
 
-	info := m.Info()
-	if fstat.Size() == int64(info.Size) {
+	if cached(name, fstat.Size(), m.Info()) {
+		cacheHits.Inc()
 		return f, nil
 	}
 
-	if err := fs.download(f, m); err != nil {
+	cacheMisses.Inc()
+	if err := fs.fill(f, m); err != nil {
 		f.Close()
-		os.Remove(name)
 		return nil, err
 	}
 
-	f.Sync()
 	f.Seek(0, io.SeekStart)
 	return f, nil
 }
 
+// fill downloads the full content of m into the (locked) cache file f
+func (fs *filesystem) fill(f *os.File, m meta.Meta) error {
+	info := m.Info()
+	for {
+		bitmap, err := os.OpenFile(bitmapPath(f.Name()), os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return err
+		}
+
+		// closing the bitmap file releases the lock
+		err = fs.fillLocked(f, bitmap, m)
+		bitmap.Close()
+		if err != errRetry {
+			return err
+		}
+
+		fstat, err := f.Stat()
+		if err != nil {
+			return err
+		}
+
+		// another process finished (or failed) the download while we were waiting
+		if cached(f.Name(), fstat.Size(), info) {
+			return nil
+		}
+	}
+}
+
+//errRetry is returned by fillLocked if the bitmap was removed while waiting for the lock
+var errRetry = fmt.Errorf("retry")
+
+func (fs *filesystem) fillLocked(f *os.File, bitmap *os.File, m meta.Meta) error {
+	if err := syscall.Flock(int(bitmap.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+
+	if ok, err := linked(bitmap); err != nil {
+		return err
+	} else if !ok {
+		return errRetry
+	}
+
+	info := m.Info()
+	if err := fs.download(f, m); err != nil {
+		f.Truncate(0)
+		os.Remove(bitmap.Name())
+		return err
+	}
+
+	cacheDownloadBytes.Add(float64(info.Size))
+	fs.manager.Add(info.Size)
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(bitmap.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
 // download file from storage
 func (fs *filesystem) download(file *os.File, m meta.Meta) error {
 	downloader := Downloader{
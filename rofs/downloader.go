@@ -8,12 +8,12 @@ import (
 	"io/ioutil"
 	"math"
 	"os"
+	"time"
 
 	"github.com/golang/snappy"
 	"github.com/threefoldtech/0-fs/meta"
 	"github.com/threefoldtech/0-fs/storage"
 	"github.com/xxtea/xxtea-go/xxtea"
-	"golang.org/x/crypto/blake2b"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -75,8 +75,10 @@ type OutputBlock struct {
 // downloadBlock downloads a data block identified by block
 func (d *Downloader) downloadBlock(block meta.BlockInfo) ([]byte, error) {
 	log.Debugf("downloading block %x", block.Key)
+	start := time.Now()
 	body, err := d.storage.Get(block.Key)
 	if err != nil {
+		blockDownloadErrors.Inc()
 		return nil, err
 	}
 
@@ -84,25 +86,24 @@ func (d *Downloader) downloadBlock(block meta.BlockInfo) ([]byte, error) {
 
 	data, err := ioutil.ReadAll(body)
 	if err != nil {
+		blockDownloadErrors.Inc()
 		return nil, err
 	}
 
+	blockDownloadDuration.Observe(time.Since(start).Seconds())
+
 	data, err = snappy.Decode(nil, xxtea.Decrypt(data, block.Decipher))
 	if err != nil {
 		return nil, err
 	}
 
-	hasher, err := blake2b.New(16, nil)
+	hash, err := blockHash(data)
 	if err != nil {
 		return nil, err
 	}
 
-	if _, err := hasher.Write(data); err != nil {
-		return nil, err
-	}
-
-	hash := hasher.Sum(nil)
 	if !bytes.Equal(hash, block.Decipher) {
+		blockHashFailures.Inc()
 		return nil, fmt.Errorf("block key(%x), cypher(%x) hash is wrong hash(%x)", block.Key, block.Decipher, hash)
 	}
 
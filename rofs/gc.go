@@ -0,0 +1,136 @@
+package rofs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/threefoldtech/0-fs/meta"
+)
+
+// CacheID returns the id under which the content of m is kept in the cache, only
+// regular files are kept in the cache
+func CacheID(m meta.Meta) (string, bool) {
+	if m.Info().Type != meta.RegularType {
+		return "", false
+	}
+
+	return m.ID(), true
+}
+
+// LiveSet collects the cache ids of all files in store, and the ids of their blocks
+// in the block cache
+func LiveSet(store meta.Walker, live map[string]struct{}) error {
+	return store.Walk("", func(path string, m meta.Meta) error {
+		id, ok := CacheID(m)
+		if !ok {
+			return nil
+		}
+
+		live[id] = struct{}{}
+		for _, block := range m.Blocks() {
+			live[blockID(block)] = struct{}{}
+		}
+
+		return nil
+	})
+}
+
+// cacheEntryID returns the id of the cache file name, or false if name is not a
+// cache file under the two-level hash layout (<root>/<id[0:2]>/<id[2:4]>/<id>) or
+// a block under the same layout in the block cache
+func cacheEntryID(root, name string) (string, bool) {
+	rel, err := filepath.Rel(root, name)
+	if err != nil {
+		return "", false
+	}
+
+	parts := strings.Split(rel, string(filepath.Separator))
+	if len(parts) == 4 && parts[0] == blocksDir {
+		parts = parts[1:]
+	}
+
+	if len(parts) != 3 {
+		return "", false
+	}
+
+	id := strings.TrimSuffix(parts[2], bitmapSuffix)
+	if len(id) < 4 || parts[0] != id[0:2] || parts[1] != id[2:4] {
+		return "", false
+	}
+
+	return id, true
+}
+
+// Unreferenced walks the cache directory root and calls fn with each cache file
+// whose id is not in live. A blocks bitmap of a partially downloaded file is only
+// reported if the file itself is missing
+func Unreferenced(root string, live map[string]struct{}, fn func(name string, info os.FileInfo) error) error {
+	return filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		id, ok := cacheEntryID(root, name)
+		if !ok {
+			return nil
+		}
+
+		if _, ok := live[id]; ok {
+			return nil
+		}
+
+		if strings.HasSuffix(name, bitmapSuffix) {
+			if _, err := os.Stat(strings.TrimSuffix(name, bitmapSuffix)); err == nil {
+				//removed with its cache file
+				return nil
+			}
+		}
+
+		return fn(name, info)
+	})
+}
+
+// Evict removes a cache file (and its blocks bitmap) if it's not in use by any
+// process. It returns false if the file is in use
+func Evict(name string) (bool, error) {
+	if strings.HasSuffix(name, bitmapSuffix) {
+		return evictBitmap(name)
+	}
+
+	return evict(name)
+}
+
+// evictBitmap removes a blocks bitmap whose cache file is missing. The bitmap is
+// kept if it's locked, since it's being updated by a download
+func evictBitmap(name string) (bool, error) {
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	defer file.Close()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err == syscall.EWOULDBLOCK {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	return true, nil
+}
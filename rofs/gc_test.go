@@ -0,0 +1,295 @@
+package rofs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/0-fs/meta"
+)
+
+type putStorage map[string][]byte
+
+func (s putStorage) Put(key, data []byte) error {
+	s[string(key)] = data
+	return nil
+}
+
+func (s putStorage) Get(key []byte) (io.ReadCloser, error) {
+	if data, ok := s[string(key)]; ok {
+		return ioutil.NopCloser(bytes.NewBuffer(data)), nil
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+func TestLiveSet(t *testing.T) {
+	src, err := ioutil.TempDir("", "gc-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	os.MkdirAll(filepath.Join(src, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "a"), []byte("content of a"), 0644)
+	ioutil.WriteFile(filepath.Join(src, "sub", "b"), []byte("content of b"), 0644)
+	os.Symlink("a", filepath.Join(src, "link"))
+
+	store, clean := writeFlist(t, src, putStorage{})
+	defer clean()
+
+	live := make(map[string]struct{})
+	if ok := assert.NoError(t, LiveSet(store.(meta.Walker), live)); !ok {
+		t.Fatal()
+	}
+
+	expected := make(map[string]struct{})
+	for _, name := range []string{"a", "sub/b"} {
+		m, _ := store.Get(name)
+		expected[m.ID()] = struct{}{}
+		for _, block := range m.Blocks() {
+			expected[blockID(block)] = struct{}{}
+		}
+	}
+
+	if ok := assert.Len(t, expected, 4); !ok {
+		t.Error()
+	}
+
+	if ok := assert.Equal(t, expected, live); !ok {
+		t.Error()
+	}
+}
+
+func TestUnreferenced(t *testing.T) {
+	root, err := ioutil.TempDir("", "gc-cache-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(root)
+
+	files := []string{
+		"ab/cd/abcdef01",
+		"ab/cd/abcdef02",
+		"ab/cd/abcdef02.blocks",
+		"12/34/12345678.blocks",
+		"ef/01/ef012345",
+		"blocks/ab/cd/abcdef03",
+		"blocks/ab/cd/abcdef04",
+		"ab/cd/not-in-layout",
+		"other",
+	}
+
+	for _, name := range files {
+		name = filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(name), 0755)
+		if ok := assert.NoError(t, ioutil.WriteFile(name, []byte("data"), 0644)); !ok {
+			t.Fatal()
+		}
+	}
+
+	//ef012345 is unreferenced but in use
+	inuse, err := os.Open(filepath.Join(root, "ef/01/ef012345"))
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer inuse.Close()
+	syscall.Flock(int(inuse.Fd()), syscall.LOCK_SH)
+
+	live := map[string]struct{}{
+		"abcdef01": {},
+		"abcdef03": {},
+	}
+
+	var found []string
+	err = Unreferenced(root, live, func(name string, info os.FileInfo) error {
+		rel, _ := filepath.Rel(root, name)
+		found = append(found, rel)
+
+		_, err := Evict(name)
+		return err
+	})
+
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	sort.Strings(found)
+	if ok := assert.Equal(t, []string{"12/34/12345678.blocks", "ab/cd/abcdef02", "blocks/ab/cd/abcdef04", "ef/01/ef012345"}, found); !ok {
+		t.Error()
+	}
+
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(root, name))
+		return err == nil
+	}
+
+	for name, expected := range map[string]bool{
+		"ab/cd/abcdef01":        true,
+		"ab/cd/abcdef02":        false,
+		"ab/cd/abcdef02.blocks": false,
+		"12/34/12345678.blocks": false,
+		"ef/01/ef012345":        true,
+		"blocks/ab/cd/abcdef03": true,
+		"blocks/ab/cd/abcdef04": false,
+		"ab/cd/not-in-layout":   true,
+		"other":                 true,
+	} {
+		if ok := assert.Equal(t, expected, exists(name), name); !ok {
+			t.Error()
+		}
+	}
+}
+
+func TestRemovePlaceholders(t *testing.T) {
+	root, err := ioutil.TempDir("", "gc-cache-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"ab/cd/abcdef01":        "",
+		"ab/cd/abcdef02":        "data",
+		"ab/cd/abcdef03":        "",
+		"ab/cd/abcdef03.blocks": "",
+		"ab/cd/abcdef04":        "",
+		"ab/cd/not-in-layout":   "",
+	}
+
+	for name, data := range files {
+		name = filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(name), 0755)
+		if ok := assert.NoError(t, ioutil.WriteFile(name, []byte(data), 0644)); !ok {
+			t.Fatal()
+		}
+	}
+
+	//in use
+	fs := &filesystem{Config: NewConfig(putStorage{}, nil, root)}
+	f, err := fs.lock(filepath.Join(root, "ab/cd/abcdef04"))
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer f.Close()
+
+	removed, err := RemovePlaceholders(root)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, 1, removed); !ok {
+		t.Error()
+	}
+
+	for name := range files {
+		_, err := os.Stat(filepath.Join(root, name))
+		if name == "ab/cd/abcdef01" {
+			if ok := assert.True(t, os.IsNotExist(err), name); !ok {
+				t.Error()
+			}
+		} else if ok := assert.NoError(t, err, name); !ok {
+			t.Error()
+		}
+	}
+}
+
+func TestRemovePlaceholdersOnce(t *testing.T) {
+	root, err := ioutil.TempDir("", "gc-cache-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(root)
+
+	removed, err := RemovePlaceholders(root)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, 0, removed); !ok {
+		t.Error()
+	}
+
+	name := filepath.Join(root, "ab/cd/abcdef01")
+	os.MkdirAll(filepath.Dir(name), 0755)
+	if ok := assert.NoError(t, ioutil.WriteFile(name, nil, 0644)); !ok {
+		t.Fatal()
+	}
+
+	//the migration already ran on this cache directory
+	removed, err = RemovePlaceholders(root)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, 0, removed); !ok {
+		t.Error()
+	}
+
+	_, err = os.Stat(name)
+	if ok := assert.NoError(t, err); !ok {
+		t.Error()
+	}
+}
+
+func TestEvictLockedBitmap(t *testing.T) {
+	root, err := ioutil.TempDir("", "gc-cache-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(root)
+
+	name := filepath.Join(root, "ab/cd/abcdef01.blocks")
+	os.MkdirAll(filepath.Dir(name), 0755)
+	if ok := assert.NoError(t, ioutil.WriteFile(name, []byte{1}, 0644)); !ok {
+		t.Fatal()
+	}
+
+	//a download is updating the bitmap
+	f, err := os.Open(name)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_EX)); !ok {
+		t.Fatal()
+	}
+
+	removed, err := Evict(name)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.False(t, removed); !ok {
+		t.Error()
+	}
+
+	f.Close()
+
+	removed, err = Evict(name)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.True(t, removed); !ok {
+		t.Error()
+	}
+
+	_, err = os.Stat(name)
+	if ok := assert.True(t, os.IsNotExist(err)); !ok {
+		t.Error()
+	}
+}
@@ -0,0 +1,356 @@
+package rofs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+const (
+	//bitmapSuffix is appended to the cache file name to get the name of its blocks bitmap
+	bitmapSuffix = ".blocks"
+)
+
+/*
+lazyFile serves reads of a cache file that is not fully downloaded yet. Only
+the blocks that cover a read are downloaded and written in place to the (sparse)
+cache file. The blocks that are already in the cache file are tracked with a
+bitmap file next to it (one bit per block).
+
+The bitmap file exists as long as the cache file is incomplete, once all blocks
+are downloaded the bitmap is removed and reads are forwarded to a loopback file.
+The cache file must be opened with filesystem.lock so it's not evicted while in use.
+
+Downloads are shared between the lazy files of a filesystem, so a block that is
+read through two opens of the same file is downloaded once.
+*/
+type lazyFile struct {
+	nodefs.File
+
+	file       *os.File
+	bitmapFile *os.File
+	downloader *Downloader
+	manager    *CacheManager
+	downloads  *blockDownloads
+	size       int64
+
+	bitmap   []byte
+	loopback nodefs.File
+
+	m sync.Mutex
+}
+
+//bitmapPath returns the path of the blocks bitmap of cache file name
+func bitmapPath(name string) string {
+	return name + bitmapSuffix
+}
+
+//newLazyFile creates a lazy file over cache file, blocks are downloaded with downloader
+func newLazyFile(file *os.File, downloader *Downloader, size int64) (*lazyFile, error) {
+	if downloader.blockSize == 0 {
+		return nil, fmt.Errorf("block size is not set")
+	}
+
+	bitmapFile, created, err := openBitmap(bitmapPath(file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	l := &lazyFile{
+		File:       nodefs.NewDefaultFile(),
+		file:       file,
+		bitmapFile: bitmapFile,
+		downloader: downloader,
+		downloads:  &blockDownloads{},
+		size:       size,
+		bitmap:     make([]byte, (len(downloader.blocks)+7)/8),
+	}
+
+	err = l.lock(func() error {
+		done, err := l.done(created)
+		if err != nil {
+			return err
+		}
+
+		if done {
+			l.m.Lock()
+			defer l.m.Unlock()
+			for index := range l.downloader.blocks {
+				l.bitmap[index/8] |= 1 << uint(index%8)
+			}
+
+			l.loopback = nodefs.NewLoopbackFile(l.file)
+			return nil
+		}
+
+		if err := l.load(); err != nil {
+			return err
+		}
+
+		l.m.Lock()
+		defer l.m.Unlock()
+		if l.complete() {
+			//the last block was written but the bitmap was not removed
+			return l.finish()
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		l.close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+//openBitmap opens the bitmap file name, created is true if it did not exist
+func openBitmap(name string) (file *os.File, created bool, err error) {
+	for {
+		file, err = os.OpenFile(name, os.O_RDWR, 0644)
+		if err == nil {
+			return file, false, nil
+		} else if !os.IsNotExist(err) {
+			return nil, false, err
+		}
+
+		file, err = os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+		if err == nil {
+			return file, true, nil
+		} else if !os.IsExist(err) {
+			return nil, false, err
+		}
+
+		//created by another process in the meantime
+	}
+}
+
+//done checks again if the cache file is complete (see cached), since it can be
+//completed by another process after it was checked and before the bitmap was
+//opened. Must be called with the bitmap file lock held
+func (l *lazyFile) done(created bool) (bool, error) {
+	fstat, err := l.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if fstat.Size() != l.size {
+		return false, nil
+	}
+
+	if created {
+		//there was no bitmap, the bitmap of the last download was removed
+		return true, os.Remove(l.bitmapFile.Name())
+	}
+
+	bstat, err := l.bitmapFile.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	//the bitmap that was opened is removed once the download is finished
+	pstat, err := os.Stat(l.bitmapFile.Name())
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return !os.SameFile(bstat, pstat), nil
+}
+
+//lock runs fn while holding an exclusive lock on the bitmap file, so the bitmap
+//can be updated safely by multiple processes sharing the same cache
+func (l *lazyFile) lock(fn func() error) error {
+	fd := int(l.bitmapFile.Fd())
+	if err := syscall.Flock(fd, syscall.LOCK_EX); err != nil {
+		return err
+	}
+
+	defer syscall.Flock(fd, syscall.LOCK_UN)
+
+	return fn()
+}
+
+//load replaces the in memory bitmap with the bitmap file, the file is the source
+//of truth since a verify repair can clear blocks from it
+func (l *lazyFile) load() error {
+	disk := make([]byte, len(l.bitmap))
+	if _, err := l.bitmapFile.ReadAt(disk, 0); err != nil && err != io.EOF {
+		return err
+	}
+
+	l.m.Lock()
+	defer l.m.Unlock()
+	copy(l.bitmap, disk)
+
+	return nil
+}
+
+func (l *lazyFile) has(index int) bool {
+	return l.bitmap[index/8]&(1<<uint(index%8)) != 0
+}
+
+//complete must be called with l.m held
+func (l *lazyFile) complete() bool {
+	for index := range l.downloader.blocks {
+		if !l.has(index) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//mark sets block index as present in the bitmap file
+func (l *lazyFile) mark(index int) error {
+	return l.lock(func() error {
+		if err := l.load(); err != nil {
+			return err
+		}
+
+		l.m.Lock()
+		defer l.m.Unlock()
+
+		//only set the bit of this block in the byte read from disk, so bits
+		//cleared by another process are not written back
+		l.bitmap[index/8] |= 1 << uint(index%8)
+		if _, err := l.bitmapFile.WriteAt(l.bitmap[index/8:index/8+1], int64(index/8)); err != nil {
+			return err
+		}
+
+		if !l.complete() {
+			return nil
+		}
+
+		return l.finish()
+	})
+}
+
+//finish is called when all blocks are there, so the cache file becomes a normal
+//cache entry. Must be called with l.m and the bitmap file lock held
+func (l *lazyFile) finish() error {
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(l.bitmapFile.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	l.loopback = nodefs.NewLoopbackFile(l.file)
+	log.Debugf("cache file %s is complete", l.file.Name())
+	return nil
+}
+
+//download makes sure block index is in the cache file
+func (l *lazyFile) download(index int) error {
+	if err := l.lock(l.load); err != nil {
+		return err
+	}
+
+	l.m.Lock()
+	has := l.has(index)
+	l.m.Unlock()
+	if has {
+		//downloaded by another process
+		return nil
+	}
+
+	data, err := l.downloader.downloadBlock(l.downloader.blocks[index])
+	if err != nil {
+		return err
+	}
+
+	if _, err := l.file.WriteAt(data, int64(index)*int64(l.downloader.blockSize)); err != nil {
+		return err
+	}
+
+	cacheDownloadBytes.Add(float64(len(data)))
+	l.manager.Add(uint64(len(data)))
+
+	return l.mark(index)
+}
+
+//fetch downloads block index if it's not in the cache file yet, concurrent
+//fetches of the same block (from any open of the file) wait for a single download
+func (l *lazyFile) fetch(index int) error {
+	key := fmt.Sprintf("%s#%d", l.file.Name(), index)
+	for {
+		l.m.Lock()
+		has := l.has(index)
+		l.m.Unlock()
+		if has {
+			return nil
+		}
+
+		var err error
+		if l.downloads.do(key, func() { err = l.download(index) }) {
+			return err
+		}
+
+		//downloaded by another open of the file, download reloads the bitmap
+		//to find out, or downloads the block again if that failed
+	}
+}
+
+func (l *lazyFile) Read(dest []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	l.m.Lock()
+	loopback := l.loopback
+	l.m.Unlock()
+
+	if loopback != nil {
+		return loopback.Read(dest, off)
+	}
+
+	if off >= l.size || len(dest) == 0 {
+		return fuse.ReadResultData(nil), fuse.OK
+	}
+
+	end := off + int64(len(dest))
+	if end > l.size {
+		end = l.size
+	}
+
+	blockSize := int64(l.downloader.blockSize)
+	for index := off / blockSize; index <= (end-1)/blockSize && index < int64(len(l.downloader.blocks)); index++ {
+		if err := l.fetch(int(index)); err != nil {
+			log.Errorf("failed to download block %d of %s: %s", index, l.file.Name(), err)
+			return nil, fuse.EIO
+		}
+	}
+
+	n, err := l.file.ReadAt(dest[:end-off], off)
+	if err != nil && err != io.EOF {
+		return nil, fuse.ToStatus(err)
+	}
+
+	return fuse.ReadResultData(dest[:n]), fuse.OK
+}
+
+func (l *lazyFile) close() {
+	l.bitmapFile.Close()
+	l.file.Close()
+}
+
+func (l *lazyFile) Flush() fuse.Status {
+	return fuse.OK
+}
+
+func (l *lazyFile) Release() {
+	l.close()
+}
+
+func (l *lazyFile) InnerFile() nodefs.File {
+	return nil
+}
+
+func (l *lazyFile) String() string {
+	return fmt.Sprintf("lazyFile(%s)", l.file.Name())
+}
@@ -0,0 +1,405 @@
+package rofs
+
+import (
+	"crypto/md5"
+	"io/ioutil"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/0-fs/meta"
+)
+
+func TestLazyFileRead(t *testing.T) {
+	storage, blocks := MakeStorage(20)
+	size := int64(len(blocks) * ChunkSize)
+
+	downloader := &Downloader{
+		storage:   storage,
+		blocks:    blocks,
+		blockSize: ChunkSize,
+	}
+
+	expected, err := ioutil.TempFile("", "lazy-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer func() {
+		expected.Close()
+		os.Remove(expected.Name())
+	}()
+
+	if ok := assert.NoError(t, downloader.Download(expected)); !ok {
+		t.Fatal()
+	}
+
+	content, err := ioutil.ReadFile(expected.Name())
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	out, err := ioutil.TempFile("", "lazy-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer func() {
+		os.Remove(out.Name())
+		os.Remove(bitmapPath(out.Name()))
+	}()
+
+	file, err := newLazyFile(out, downloader, size)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer file.Release()
+
+	//a read that crosses blocks 3 and 4
+	off := int64(4*ChunkSize - 10)
+	buf := make([]byte, 20)
+	result, status := file.Read(buf, off)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	data, _ := result.Bytes(nil)
+	if ok := assert.Equal(t, content[off:off+20], data); !ok {
+		t.Error()
+	}
+
+	for index := range blocks {
+		if ok := assert.Equal(t, index == 3 || index == 4, file.has(index), "block %d", index); !ok {
+			t.Error()
+		}
+	}
+
+	if ok := assert.False(t, cached(out.Name(), size, meta.Info{Size: uint64(size)})); !ok {
+		t.Error()
+	}
+
+	//read past the end of the file
+	buf = make([]byte, 2*ChunkSize)
+	result, status = file.Read(buf, size-10)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	data, _ = result.Bytes(nil)
+	if ok := assert.Equal(t, content[size-10:], data); !ok {
+		t.Error()
+	}
+
+	//read everything
+	hash := md5.New()
+	for off := int64(0); off < size; off += int64(len(buf)) {
+		result, status := file.Read(buf, off)
+		if ok := assert.Equal(t, fuse.OK, status); !ok {
+			t.Fatal()
+		}
+
+		data, _ := result.Bytes(nil)
+		hash.Write(data)
+	}
+
+	if ok := assert.Equal(t, storage.hash, hash.Sum(nil)); !ok {
+		t.Error("wrong hash")
+	}
+
+	if ok := assert.NotNil(t, file.loopback); !ok {
+		t.Error()
+	}
+
+	if ok := assert.True(t, cached(out.Name(), size, meta.Info{Size: uint64(size)})); !ok {
+		t.Error()
+	}
+}
+
+func TestLazyFileResume(t *testing.T) {
+	storage, blocks := MakeStorage(4)
+	size := int64(len(blocks) * ChunkSize)
+
+	downloader := &Downloader{
+		storage:   storage,
+		blocks:    blocks,
+		blockSize: ChunkSize,
+	}
+
+	out, err := ioutil.TempFile("", "lazy-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	name := out.Name()
+	defer func() {
+		os.Remove(name)
+		os.Remove(bitmapPath(name))
+	}()
+
+	file, err := newLazyFile(out, downloader, size)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	_, status := file.Read(make([]byte, 10), 0)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	file.Release()
+
+	//drop the block that was downloaded, a new lazy file must not fetch it again
+	delete(storage.data, "block-0")
+
+	out, err = os.OpenFile(name, os.O_RDWR, 0)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	file, err = newLazyFile(out, downloader, size)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer file.Release()
+
+	if ok := assert.True(t, file.has(0)); !ok {
+		t.Error()
+	}
+
+	_, status = file.Read(make([]byte, 10), 0)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Error()
+	}
+}
+
+func TestLazyFileRepair(t *testing.T) {
+	storage, blocks := MakeStorage(4)
+	size := int64(len(blocks) * ChunkSize)
+
+	downloader := &Downloader{
+		storage:   storage,
+		blocks:    blocks,
+		blockSize: ChunkSize,
+	}
+
+	out, err := ioutil.TempFile("", "lazy-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	name := out.Name()
+	defer func() {
+		os.Remove(name)
+		os.Remove(bitmapPath(name))
+	}()
+
+	file, err := newLazyFile(out, downloader, size)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer file.Release()
+
+	_, status := file.Read(make([]byte, 10), 0)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	//a repair clears block 0 from the bitmap file while the file is open
+	if ok := assert.NoError(t, ioutil.WriteFile(bitmapPath(name), []byte{0}, 0644)); !ok {
+		t.Fatal()
+	}
+
+	_, status = file.Read(make([]byte, 10), ChunkSize)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	bitmap, err := ioutil.ReadFile(bitmapPath(name))
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, []byte{1 << 1}, bitmap); !ok {
+		t.Error()
+	}
+
+	if ok := assert.False(t, file.has(0)); !ok {
+		t.Error()
+	}
+}
+
+func TestLazyFileCompleted(t *testing.T) {
+	storage, blocks := MakeStorage(4)
+	size := int64(len(blocks) * ChunkSize)
+
+	downloader := &Downloader{
+		storage:   storage,
+		blocks:    blocks,
+		blockSize: ChunkSize,
+	}
+
+	out, err := ioutil.TempFile("", "lazy-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	name := out.Name()
+	defer func() {
+		os.Remove(name)
+		os.Remove(bitmapPath(name))
+	}()
+
+	file, err := newLazyFile(out, downloader, size)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer file.Release()
+
+	//opened before the file is completed by another open
+	other, err := os.OpenFile(name, os.O_RDWR, 0)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	second, err := newLazyFile(other, downloader, size)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer second.Release()
+
+	_, status := file.Read(make([]byte, size), 0)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.True(t, cached(name, size, meta.Info{Size: uint64(size)})); !ok {
+		t.Fatal()
+	}
+
+	var done bool
+	err = second.lock(func() (err error) {
+		done, err = second.done(false)
+		return
+	})
+
+	if ok := assert.NoError(t, err); !ok {
+		t.Error()
+	}
+
+	if ok := assert.True(t, done); !ok {
+		t.Error()
+	}
+
+	//a complete file is served as is, without creating a bitmap
+	storage.data = map[string][]byte{}
+	out, err = os.OpenFile(name, os.O_RDWR, 0)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	third, err := newLazyFile(out, downloader, size)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer third.Release()
+
+	if ok := assert.NotNil(t, third.loopback); !ok {
+		t.Error()
+	}
+
+	_, err = os.Stat(bitmapPath(name))
+	if ok := assert.True(t, os.IsNotExist(err)); !ok {
+		t.Error()
+	}
+
+	_, status = third.Read(make([]byte, 10), 0)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Error()
+	}
+}
+
+//slowStorage counts the blocks that are downloaded, each download takes a while
+type slowStorage struct {
+	*TestStorage
+	gets int
+	m    sync.Mutex
+}
+
+func (s *slowStorage) Get(key []byte) (io.ReadCloser, error) {
+	s.m.Lock()
+	s.gets++
+	s.m.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+	return s.TestStorage.Get(key)
+}
+
+func TestLazyFileSharedDownloads(t *testing.T) {
+	test, blocks := MakeStorage(4)
+	storage := &slowStorage{TestStorage: test}
+	size := int64(len(blocks) * ChunkSize)
+
+	downloader := &Downloader{
+		storage:   storage,
+		blocks:    blocks,
+		blockSize: ChunkSize,
+	}
+
+	out, err := ioutil.TempFile("", "lazy-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	name := out.Name()
+	defer func() {
+		os.Remove(name)
+		os.Remove(bitmapPath(name))
+	}()
+
+	var downloads blockDownloads
+	var files []*lazyFile
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(name, os.O_RDWR, 0)
+		if ok := assert.NoError(t, err); !ok {
+			t.Fatal()
+		}
+
+		file, err := newLazyFile(f, downloader, size)
+		if ok := assert.NoError(t, err); !ok {
+			t.Fatal()
+		}
+
+		defer file.Release()
+		file.downloads = &downloads
+		files = append(files, file)
+	}
+
+	out.Close()
+
+	//both opens of the file read the same block at the same time
+	var wg sync.WaitGroup
+	for _, file := range files {
+		wg.Add(1)
+		go func(file *lazyFile) {
+			defer wg.Done()
+			_, status := file.Read(make([]byte, 10), 0)
+			assert.Equal(t, fuse.OK, status)
+		}(file)
+	}
+
+	wg.Wait()
+
+	if ok := assert.Equal(t, 1, storage.gets); !ok {
+		t.Error()
+	}
+}
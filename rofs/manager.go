@@ -0,0 +1,302 @@
+package rofs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	//DefaultHighWatermark is the cache usage (fraction of the cache size) that starts an eviction
+	DefaultHighWatermark = 0.9
+	//DefaultLowWatermark is the cache usage (fraction of the cache size) an eviction goes down to
+	DefaultLowWatermark = 0.8
+
+	cacheScanInterval = 1 * time.Minute
+)
+
+// CacheManagerOption interface
+type CacheManagerOption interface {
+	apply(c *CacheManager)
+}
+
+type watermarksOpt struct {
+	low, high float64
+}
+
+func (o watermarksOpt) apply(c *CacheManager) {
+	c.low = o.low
+	c.high = o.high
+}
+
+// WithWatermarks sets the low and high watermarks as fractions of the cache size.
+// Eviction starts when usage goes above high, and removes files until usage is below low
+func WithWatermarks(low, high float64) CacheManagerOption {
+	return watermarksOpt{low: low, high: high}
+}
+
+/*
+CacheManager keeps the size of a cache directory under a budget. Files are
+evicted in least recently used order, using the modification time of the cache
+files which is updated each time a file is opened, so multiple processes that
+share the same cache directory agree on the order.
+
+A file is never evicted while it's open or being downloaded, since both hold
+a lock on the file (see lock). This also applies to other processes using the
+same cache directory.
+*/
+type CacheManager struct {
+	root string
+	size uint64
+	low  float64
+	high float64
+
+	used    uint64
+	trigger chan struct{}
+	stop    chan struct{}
+	o       sync.Once
+
+	m sync.Mutex
+}
+
+type cacheEntry struct {
+	name  string
+	size  uint64
+	atime time.Time
+}
+
+// NewCacheManager creates a cache manager for the cache directory root with a budget of size bytes
+func NewCacheManager(root string, size uint64, opts ...CacheManagerOption) (*CacheManager, error) {
+	manager := &CacheManager{
+		root:    root,
+		size:    size,
+		low:     DefaultLowWatermark,
+		high:    DefaultHighWatermark,
+		trigger: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt.apply(manager)
+	}
+
+	if size == 0 {
+		return nil, fmt.Errorf("invalid cache size")
+	}
+
+	if manager.low <= 0 || manager.high > 1 || manager.low > manager.high {
+		return nil, fmt.Errorf("invalid watermarks low: %v, high: %v", manager.low, manager.high)
+	}
+
+	return manager, nil
+}
+
+// Start runs the eviction in the background until Stop is called
+func (c *CacheManager) Start() {
+	go c.run()
+}
+
+// Stop stops the background eviction
+func (c *CacheManager) Stop() {
+	c.o.Do(func() {
+		close(c.stop)
+	})
+}
+
+func (c *CacheManager) run() {
+	ticker := time.NewTicker(cacheScanInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Evict(); err != nil {
+			log.Errorf("cache eviction failed: %s", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-c.trigger:
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Add records that n bytes were added to the cache, and triggers an eviction
+// if the cache is above the high watermark
+func (c *CacheManager) Add(n uint64) {
+	if c == nil {
+		return
+	}
+
+	c.m.Lock()
+	c.used += n
+	over := c.used > c.highBytes()
+	c.m.Unlock()
+
+	if !over {
+		return
+	}
+
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Touch marks the cache file name as recently used
+func (c *CacheManager) Touch(name string) {
+	if c == nil {
+		return
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(name, now, now); err != nil {
+		log.Warningf("failed to update cache file access time: %s", err)
+	}
+}
+
+func (c *CacheManager) highBytes() uint64 {
+	return uint64(float64(c.size) * c.high)
+}
+
+func (c *CacheManager) lowBytes() uint64 {
+	return uint64(float64(c.size) * c.low)
+}
+
+// usage returns the space used on disk by a file, cache files can be sparse
+func usage(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat != nil {
+		return uint64(stat.Blocks) * 512
+	}
+
+	return uint64(info.Size())
+}
+
+func (c *CacheManager) scan() ([]cacheEntry, uint64, error) {
+	var entries []cacheEntry
+	var used uint64
+
+	err := filepath.Walk(c.root, func(name string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			//removed while walking
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		used += usage(info)
+		if strings.HasSuffix(name, bitmapSuffix) {
+			//evicted with its cache file
+			return nil
+		}
+
+		if strings.HasPrefix(info.Name(), ".tmp-") {
+			//block being written to the block cache
+			return nil
+		}
+
+		if name == filepath.Join(c.root, placeholdersMarker) {
+			return nil
+		}
+
+		entries = append(entries, cacheEntry{
+			name:  name,
+			size:  usage(info),
+			atime: info.ModTime(),
+		})
+
+		return nil
+	})
+
+	return entries, used, err
+}
+
+// evict removes cache file name if it's not in use, and returns true if it was removed
+func evict(name string) (bool, error) {
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	defer file.Close()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err == syscall.EWOULDBLOCK {
+		//file is open or being downloaded
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	if err := os.Remove(bitmapPath(name)); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Evict scans the cache directory, and if its usage is above the high watermark
+// it removes the least recently used files until usage is below the low watermark
+func (c *CacheManager) Evict() error {
+	entries, used, err := c.scan()
+	if err != nil {
+		return err
+	}
+
+	c.m.Lock()
+	c.used = used
+	c.m.Unlock()
+
+	if used <= c.highBytes() {
+		return nil
+	}
+
+	log.Infof("cache usage %d bytes is above high watermark, evicting", used)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].atime.Before(entries[j].atime)
+	})
+
+	low := c.lowBytes()
+	for _, entry := range entries {
+		if used <= low {
+			break
+		}
+
+		evicted, err := evict(entry.name)
+		if err != nil {
+			log.Errorf("failed to evict cache file %s: %s", entry.name, err)
+			continue
+		} else if !evicted {
+			continue
+		}
+
+		cacheEvictions.Inc()
+		used -= entry.size
+	}
+
+	c.m.Lock()
+	c.used = used
+	c.m.Unlock()
+
+	if used > low {
+		log.Warningf("cache usage %d bytes is still above low watermark, files are in use", used)
+	}
+
+	return nil
+}
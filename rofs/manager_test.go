@@ -0,0 +1,96 @@
+package rofs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheManagerEvict(t *testing.T) {
+	root, err := ioutil.TempDir("", "cache-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(root)
+
+	const fileSize = 64 * 1024
+	data := make([]byte, fileSize)
+	now := time.Now()
+
+	//file-0 is the least recently used
+	var names []string
+	for i, name := range []string{"aa/bb/file-0", "aa/cc/file-1", "bb/cc/file-2", "cc/dd/file-3"} {
+		name = filepath.Join(root, name)
+		if ok := assert.NoError(t, os.MkdirAll(filepath.Dir(name), 0755)); !ok {
+			t.Fatal()
+		}
+
+		if ok := assert.NoError(t, ioutil.WriteFile(name, data, 0444)); !ok {
+			t.Fatal()
+		}
+
+		atime := now.Add(time.Duration(i-10) * time.Minute)
+		if ok := assert.NoError(t, os.Chtimes(name, atime, atime)); !ok {
+			t.Fatal()
+		}
+
+		names = append(names, name)
+	}
+
+	//file-0 is partially downloaded
+	if ok := assert.NoError(t, ioutil.WriteFile(bitmapPath(names[0]), []byte{1}, 0644)); !ok {
+		t.Fatal()
+	}
+
+	//file-1 is in use
+	inuse, err := os.Open(names[1])
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer inuse.Close()
+	if ok := assert.NoError(t, syscall.Flock(int(inuse.Fd()), syscall.LOCK_SH)); !ok {
+		t.Fatal()
+	}
+
+	//4 files over a budget of 3 files, 2 files must go
+	manager, err := NewCacheManager(root, 3*fileSize+fileSize/2, WithWatermarks(0.6, 0.9))
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.NoError(t, manager.Evict()); !ok {
+		t.Fatal()
+	}
+
+	for i, name := range names {
+		_, err := os.Stat(name)
+		exists := err == nil
+		if ok := assert.Equal(t, i == 1 || i == 3, exists, "file-%d", i); !ok {
+			t.Error()
+		}
+	}
+
+	_, err = os.Stat(bitmapPath(names[0]))
+	if ok := assert.True(t, os.IsNotExist(err)); !ok {
+		t.Error()
+	}
+}
+
+func TestCacheManagerInvalid(t *testing.T) {
+	_, err := NewCacheManager(os.TempDir(), 0)
+	if ok := assert.Error(t, err); !ok {
+		t.Error()
+	}
+
+	_, err = NewCacheManager(os.TempDir(), 1024, WithWatermarks(0.9, 0.8))
+	if ok := assert.Error(t, err); !ok {
+		t.Error()
+	}
+}
@@ -0,0 +1,110 @@
+package rofs
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	metricsNamespace = "zerofs"
+)
+
+var (
+	fuseOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "fuse",
+		Name:      "operations_total",
+		Help:      "Number of fuse operations served by the read-only layer",
+	}, []string{"operation"})
+
+	fuseOperationsDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "fuse",
+		Name:      "operation_duration_seconds",
+		Help:      "Latency of fuse operations served by the read-only layer",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"operation"})
+
+	cacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "cache",
+		Name:      "hits_total",
+		Help:      "Number of opened files that were found complete in the cache",
+	})
+
+	cacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "cache",
+		Name:      "misses_total",
+		Help:      "Number of opened files that had to be downloaded",
+	})
+
+	cacheBlockHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "cache",
+		Name:      "block_hits_total",
+		Help:      "Number of blocks read from the block cache",
+	})
+
+	cacheBlockMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "cache",
+		Name:      "block_misses_total",
+		Help:      "Number of blocks that had to be downloaded into the block cache",
+	})
+
+	cacheDownloadBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "cache",
+		Name:      "download_bytes_total",
+		Help:      "Number of bytes downloaded into the cache",
+	})
+
+	cacheEvictions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "cache",
+		Name:      "evictions_total",
+		Help:      "Number of files evicted from the cache",
+	})
+
+	cacheCorruptBlocks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "cache",
+		Name:      "corrupt_blocks_total",
+		Help:      "Number of corrupt blocks found in cache files",
+	})
+
+	blockDownloadDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "downloader",
+		Name:      "block_duration_seconds",
+		Help:      "Latency of block downloads",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	})
+
+	blockDownloadErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "downloader",
+		Name:      "block_errors_total",
+		Help:      "Number of blocks that failed to download",
+	})
+
+	blockHashFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "downloader",
+		Name:      "block_hash_failures_total",
+		Help:      "Number of downloaded blocks with a wrong hash",
+	})
+)
+
+//observe counts a fuse operation, the returned function must be called when
+//the operation is done to record its latency
+func observe(operation string) func() {
+	start := time.Now()
+	return func() {
+		fuseOperations.WithLabelValues(operation).Inc()
+		fuseOperationsDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	}
+}
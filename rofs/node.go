@@ -0,0 +1,274 @@
+package rofs
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	fusefs "github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/threefoldtech/0-fs/meta"
+)
+
+/*
+node is an entry of the filesystem served with the inode based go-fuse API (fs).
+Each node keeps the meta of its entry, so lookups go through the children of the
+parent directory instead of a path lookup in the store, and the kernel can cache
+the entries and attributes.
+
+When the store is changed, the meta of a node is looked up again by path in the
+new store.
+*/
+type node struct {
+	fusefs.Inode
+
+	fs   *filesystem
+	path string
+
+	m          meta.Meta
+	generation uint64
+	children   map[string]meta.Meta
+	mu         sync.Mutex
+}
+
+var (
+	_ fusefs.NodeGetattrer   = (*node)(nil)
+	_ fusefs.NodeLookuper    = (*node)(nil)
+	_ fusefs.NodeReaddirer   = (*node)(nil)
+	_ fusefs.NodeOpener      = (*node)(nil)
+	_ fusefs.NodeReadlinker  = (*node)(nil)
+	_ fusefs.NodeGetxattrer  = (*node)(nil)
+	_ fusefs.NodeListxattrer = (*node)(nil)
+	_ fusefs.NodeStatfser    = (*node)(nil)
+)
+
+//NewRoot creates the root node of the filesystem for the inode based go-fuse API
+func NewRoot(cfg *Config) (fusefs.InodeEmbedder, error) {
+	generation := atomic.LoadUint64(&cfg.generation)
+	m, ok := cfg.getStore().Get("")
+	if !ok {
+		return nil, fmt.Errorf("flist has no root directory")
+	}
+
+	return &node{fs: &filesystem{Config: cfg}, m: m, generation: generation}, nil
+}
+
+// load returns the meta of the node, it's looked up again if the store was changed.
+// It must be called with the node lock held
+func (n *node) load() (meta.Meta, bool) {
+	generation := atomic.LoadUint64(&n.fs.generation)
+	if n.generation != generation {
+		m, ok := n.fs.getStore().Get(n.path)
+		if !ok {
+			m = nil
+		}
+
+		n.m, n.generation, n.children = m, generation, nil
+	}
+
+	return n.m, n.m != nil
+}
+
+// meta returns the meta of the node, or false if the entry was removed from the store
+func (n *node) meta() (meta.Meta, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.load()
+}
+
+// child returns the meta of the child name and the store generation it was read from
+func (n *node) child(name string) (meta.Meta, uint64, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	m, ok := n.load()
+	if !ok {
+		return nil, 0, false
+	}
+
+	if n.children == nil {
+		n.children = make(map[string]meta.Meta)
+		for _, child := range m.Children() {
+			n.children[child.Name()] = child
+		}
+	}
+
+	child, ok := n.children[name]
+	return child, n.generation, ok
+}
+
+func (n *node) Getattr(ctx context.Context, f fusefs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	log.Debugf("Getattr %s", n.path)
+	defer observe("getattr")()
+
+	m, ok := n.meta()
+	if !ok {
+		return syscall.ENOENT
+	}
+
+	out.Attr = *attr(n.path, m.Info())
+	return fusefs.OK
+}
+
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fusefs.Inode, syscall.Errno) {
+	log.Debugf("Lookup %s %s", n.path, name)
+	defer observe("lookup")()
+
+	m, generation, ok := n.child(name)
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+
+	info := m.Info()
+	if info.Type == meta.UnknownType {
+		return nil, syscall.EIO
+	}
+
+	child := &node{fs: n.fs, path: path.Join(n.path, name), m: m, generation: generation}
+	out.Attr = *attr(child.path, info)
+
+	stable := fusefs.StableAttr{Mode: uint32(info.Type), Ino: out.Attr.Ino}
+	if stable.Reserved() {
+		//let go-fuse pick an inode number
+		stable.Ino = 0
+	}
+
+	return n.NewInode(ctx, child, stable), fusefs.OK
+}
+
+func (n *node) Readdir(ctx context.Context) (fusefs.DirStream, syscall.Errno) {
+	log.Debugf("Readdir %s", n.path)
+	defer observe("opendir")()
+
+	m, ok := n.meta()
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+
+	n.fs.tracer.Record(TraceOpenDir, n.path)
+	return fusefs.NewListDirStream(dirEntries(n.path, m)), fusefs.OK
+}
+
+func (n *node) Open(ctx context.Context, flags uint32) (fusefs.FileHandle, uint32, syscall.Errno) {
+	log.Debugf("Open %s", n.path)
+	defer observe("open")()
+
+	if flags&fuse.O_ANYWRITE != 0 {
+		return nil, 0, syscall.EPERM
+	}
+
+	m, ok := n.meta()
+	if !ok {
+		return nil, 0, syscall.ENOENT
+	}
+
+	n.fs.tracer.Record(TraceOpen, n.path)
+	f, err := n.fs.open(m)
+	if err != nil {
+		log.Errorf("Failed to open/download the file: %s", err)
+		return nil, 0, syscall.EIO
+	}
+
+	//flists are immutable, the page cache of the file stays valid
+	return &handle{File: f}, fuse.FOPEN_KEEP_CACHE, fusefs.OK
+}
+
+func (n *node) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	log.Debugf("Readlink %s", n.path)
+	defer observe("readlink")()
+
+	m, ok := n.meta()
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+
+	n.fs.tracer.Record(TraceReadlink, n.path)
+	return []byte(m.Info().LinkTarget), fusefs.OK
+}
+
+func (n *node) Getxattr(ctx context.Context, name string, dest []byte) (uint32, syscall.Errno) {
+	log.Debugf("Getxattr %s %s", n.path, name)
+	defer observe("getxattr")()
+
+	m, ok := n.meta()
+	if !ok {
+		return 0, syscall.ENOENT
+	}
+
+	value, ok := m.XAttrs()[name]
+	if !ok {
+		return 0, syscall.Errno(fuse.ENOATTR)
+	}
+
+	if len(dest) < len(value) {
+		return uint32(len(value)), syscall.ERANGE
+	}
+
+	return uint32(copy(dest, value)), fusefs.OK
+}
+
+func (n *node) Listxattr(ctx context.Context, dest []byte) (uint32, syscall.Errno) {
+	log.Debugf("Listxattr %s", n.path)
+	defer observe("listxattr")()
+
+	m, ok := n.meta()
+	if !ok {
+		return 0, syscall.ENOENT
+	}
+
+	names := xattrNames(m)
+
+	var size int
+	for _, name := range names {
+		size += len(name) + 1
+	}
+
+	if len(dest) < size {
+		return uint32(size), syscall.ERANGE
+	}
+
+	var offset int
+	for _, name := range names {
+		offset += copy(dest[offset:], name)
+		dest[offset] = 0
+		offset++
+	}
+
+	return uint32(size), fusefs.OK
+}
+
+func (n *node) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
+	*out = *n.fs.StatFs(n.path)
+	return fusefs.OK
+}
+
+// handle serves the files returned by open (cache files, lazy and block files)
+// through the inode based API
+type handle struct {
+	nodefs.File
+}
+
+var (
+	_ fusefs.FileReader   = (*handle)(nil)
+	_ fusefs.FileFlusher  = (*handle)(nil)
+	_ fusefs.FileReleaser = (*handle)(nil)
+)
+
+func (h *handle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	result, status := h.File.Read(dest, off)
+	return result, syscall.Errno(status)
+}
+
+func (h *handle) Flush(ctx context.Context) syscall.Errno {
+	return syscall.Errno(h.File.Flush())
+}
+
+func (h *handle) Release(ctx context.Context) syscall.Errno {
+	h.File.Release()
+	return fusefs.OK
+}
@@ -0,0 +1,473 @@
+package rofs
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	fusefs "github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/0-fs/meta"
+	"golang.org/x/sys/unix"
+)
+
+// nodeTree creates an flist of the src directory, and returns the root node of
+// the filesystem that serves it
+func nodeTree(t *testing.T, src string) (*node, func()) {
+	storage := putStorage{}
+	store, clean := writeFlist(t, src, storage)
+
+	cache, err := ioutil.TempDir("", "node-cache-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	root, err := NewRoot(NewConfig(storage, store, cache))
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	//attach the root to a (not mounted) go-fuse bridge, so nodes can be looked up
+	fusefs.NewNodeFS(root, &fusefs.Options{})
+
+	return root.(*node), func() {
+		clean()
+		os.RemoveAll(cache)
+	}
+}
+
+// lookup walks the nodes from root to name
+func lookup(root *node, name string) (*node, fuse.EntryOut, syscall.Errno) {
+	var out fuse.EntryOut
+	n := root
+	for _, part := range strings.Split(name, "/") {
+		inode, errno := n.Lookup(context.Background(), part, &out)
+		if errno != 0 {
+			return nil, out, errno
+		}
+
+		n = inode.Operations().(*node)
+	}
+
+	return n, out, 0
+}
+
+func TestNodeLookup(t *testing.T) {
+	src, err := ioutil.TempDir("", "node-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	os.MkdirAll(filepath.Join(src, "etc", "ssl"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "etc", "hostname"), []byte("zos"), 0644)
+	os.Symlink("hostname", filepath.Join(src, "etc", "name"))
+
+	root, clean := nodeTree(t, src)
+	defer clean()
+
+	entries, errno := root.Readdir(context.Background())
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.True(t, entries.HasNext()); !ok {
+		t.Fatal()
+	}
+
+	entry, _ := entries.Next()
+	if ok := assert.Equal(t, "etc", entry.Name); !ok {
+		t.Error()
+	}
+
+	if ok := assert.EqualValues(t, meta.InodeNumber("etc")^uint64(meta.DirType), entry.Ino); !ok {
+		t.Error()
+	}
+
+	hostname, out, errno := lookup(root, "etc/hostname")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.EqualValues(t, 3, out.Size); !ok {
+		t.Error()
+	}
+
+	if ok := assert.EqualValues(t, syscall.S_IFREG|0644, out.Mode); !ok {
+		t.Error()
+	}
+
+	if ok := assert.EqualValues(t, 1, out.Nlink); !ok {
+		t.Error()
+	}
+
+	var attr fuse.AttrOut
+	if ok := assert.Equal(t, fusefs.OK, root.Getattr(context.Background(), nil, &attr)); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.EqualValues(t, 2, attr.Nlink); !ok {
+		t.Error()
+	}
+
+	if ok := assert.Equal(t, meta.InodeNumber("etc/hostname")^uint64(meta.RegularType), hostname.StableAttr().Ino); !ok {
+		t.Error()
+	}
+
+	//looking up the same entry again gives the same node
+	again, _, _ := lookup(root, "etc/hostname")
+	if ok := assert.Equal(t, hostname.EmbeddedInode(), again.EmbeddedInode()); !ok {
+		t.Error()
+	}
+
+	name, _, errno := lookup(root, "etc/name")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	target, errno := name.Readlink(context.Background())
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, "hostname", string(target)); !ok {
+		t.Error()
+	}
+
+	_, _, errno = lookup(root, "etc/missing")
+	if ok := assert.Equal(t, syscall.ENOENT, errno); !ok {
+		t.Error()
+	}
+
+	_, _, errno = lookup(root, "etc/hostname/missing")
+	if ok := assert.Equal(t, syscall.ENOENT, errno); !ok {
+		t.Error()
+	}
+}
+
+func TestNodeOpen(t *testing.T) {
+	src, err := ioutil.TempDir("", "node-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	content := bytes.Repeat([]byte("0-fs"), 4096)
+	ioutil.WriteFile(filepath.Join(src, "data"), content, 0644)
+
+	root, clean := nodeTree(t, src)
+	defer clean()
+
+	data, _, errno := lookup(root, "data")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	_, _, errno = data.Open(context.Background(), syscall.O_RDWR)
+	if ok := assert.Equal(t, syscall.EPERM, errno); !ok {
+		t.Error()
+	}
+
+	f, flags, errno := data.Open(context.Background(), syscall.O_RDONLY)
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	defer f.(fusefs.FileReleaser).Release(context.Background())
+
+	if ok := assert.EqualValues(t, fuse.FOPEN_KEEP_CACHE, flags); !ok {
+		t.Error()
+	}
+
+	buf := make([]byte, len(content))
+	result, errno := f.(fusefs.FileReader).Read(context.Background(), buf, 0)
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	read, status := result.Bytes(buf)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, content, read); !ok {
+		t.Error()
+	}
+}
+
+func TestNodeXAttr(t *testing.T) {
+	src, err := ioutil.TempDir("", "node-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	ping := filepath.Join(src, "ping")
+	ioutil.WriteFile(ping, []byte("ping"), 0755)
+	if err := unix.Setxattr(ping, "user.first", []byte("first value"), 0); err == unix.ENOTSUP {
+		t.Skipf("extended attributes are not supported: %s", err)
+	} else if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	unix.Setxattr(ping, "user.second", []byte{}, 0)
+
+	root, clean := nodeTree(t, src)
+	defer clean()
+
+	n, _, errno := lookup(root, "ping")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	size, errno := n.Listxattr(context.Background(), nil)
+	if ok := assert.Equal(t, syscall.ERANGE, errno); !ok {
+		t.Error()
+	}
+
+	buf := make([]byte, size)
+	size, errno = n.Listxattr(context.Background(), buf)
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, "user.first\x00user.second\x00", string(buf[:size])); !ok {
+		t.Error()
+	}
+
+	size, errno = n.Getxattr(context.Background(), "user.first", nil)
+	if ok := assert.Equal(t, syscall.ERANGE, errno); !ok {
+		t.Error()
+	}
+
+	buf = make([]byte, size)
+	size, errno = n.Getxattr(context.Background(), "user.first", buf)
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, "first value", string(buf[:size])); !ok {
+		t.Error()
+	}
+
+	_, errno = n.Getxattr(context.Background(), "user.missing", buf)
+	if ok := assert.Equal(t, syscall.Errno(fuse.ENOATTR), errno); !ok {
+		t.Error()
+	}
+}
+
+func TestNodeSpecial(t *testing.T) {
+	src, err := ioutil.TempDir("", "node-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	if ok := assert.NoError(t, unix.Mkfifo(filepath.Join(src, "fifo"), 0620)); !ok {
+		t.Fatal()
+	}
+
+	err = unix.Mknod(filepath.Join(src, "ttyS"), unix.S_IFCHR|0660, int(unix.Mkdev(4, 300)))
+	devices := err == nil
+	if err == unix.EPERM {
+		t.Logf("can't create device nodes: %s", err)
+	} else if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	root, clean := nodeTree(t, src)
+	defer clean()
+
+	_, out, errno := lookup(root, "fifo")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	//modes are compared to the source since they are subject to umask
+	var stat unix.Stat_t
+	unix.Lstat(filepath.Join(src, "fifo"), &stat)
+	if ok := assert.Equal(t, stat.Mode, out.Mode); !ok {
+		t.Error()
+	}
+
+	if !devices {
+		return
+	}
+
+	_, out, errno = lookup(root, "ttyS")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	unix.Lstat(filepath.Join(src, "ttyS"), &stat)
+	if ok := assert.Equal(t, stat.Mode, out.Mode); !ok {
+		t.Error()
+	}
+
+	if ok := assert.EqualValues(t, 4, unix.Major(uint64(out.Rdev))); !ok {
+		t.Error()
+	}
+
+	if ok := assert.EqualValues(t, 300, unix.Minor(uint64(out.Rdev))); !ok {
+		t.Error()
+	}
+}
+
+func TestNodeHardlink(t *testing.T) {
+	src, err := ioutil.TempDir("", "node-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	ioutil.WriteFile(filepath.Join(src, "busybox"), []byte("busybox"), 0755)
+	if ok := assert.NoError(t, os.Link(filepath.Join(src, "busybox"), filepath.Join(src, "sh"))); !ok {
+		t.Fatal()
+	}
+
+	root, clean := nodeTree(t, src)
+	defer clean()
+
+	busybox, out, errno := lookup(root, "busybox")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.EqualValues(t, 2, out.Nlink); !ok {
+		t.Error()
+	}
+
+	//both links are the same inode
+	sh, _, errno := lookup(root, "sh")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, busybox.EmbeddedInode(), sh.EmbeddedInode()); !ok {
+		t.Error()
+	}
+}
+
+func TestNodeStatfs(t *testing.T) {
+	src, err := ioutil.TempDir("", "node-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	ioutil.WriteFile(filepath.Join(src, "data"), make([]byte, 5000), 0644)
+
+	root, clean := nodeTree(t, src)
+	defer clean()
+
+	var out fuse.StatfsOut
+	if ok := assert.Equal(t, fusefs.OK, root.Statfs(context.Background(), &out)); !ok {
+		t.Fatal()
+	}
+
+	//the first call starts computing the usage
+	if ok := assert.True(t, waitUsage(root.fs)); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, fusefs.OK, root.Statfs(context.Background(), &out)); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.EqualValues(t, 2, out.Blocks-out.Bfree); !ok {
+		t.Error()
+	}
+
+	//root and data
+	if ok := assert.EqualValues(t, 2, out.Files-out.Ffree); !ok {
+		t.Error()
+	}
+}
+
+func TestNodeSetMetaStore(t *testing.T) {
+	src, err := ioutil.TempDir("", "node-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	os.MkdirAll(filepath.Join(src, "etc"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "etc", "hostname"), []byte("zos"), 0644)
+	ioutil.WriteFile(filepath.Join(src, "etc", "hosts"), []byte("127.0.0.1 localhost"), 0644)
+	ioutil.WriteFile(filepath.Join(src, "etc", "motd"), []byte("welcome"), 0644)
+
+	root, clean := nodeTree(t, src)
+	defer clean()
+
+	hostname, _, errno := lookup(root, "etc/hostname")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	hosts, _, errno := lookup(root, "etc/hosts")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	motd, _, errno := lookup(root, "etc/motd")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	//a new version of the flist
+	ioutil.WriteFile(filepath.Join(src, "etc", "hostname"), []byte("zero-os"), 0644)
+	os.Remove(filepath.Join(src, "etc", "hosts"))
+	ioutil.WriteFile(filepath.Join(src, "etc", "resolv.conf"), []byte("nameserver 1.1.1.1"), 0644)
+	os.Remove(filepath.Join(src, "etc", "motd"))
+	os.Mkdir(filepath.Join(src, "etc", "motd"), 0755)
+
+	store, cleanStore := writeFlist(t, src, putStorage{})
+	defer cleanStore()
+
+	root.fs.SetMetaStore(store)
+
+	var out fuse.AttrOut
+	if ok := assert.Equal(t, fusefs.OK, hostname.Getattr(context.Background(), nil, &out)); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.EqualValues(t, 7, out.Size); !ok {
+		t.Error()
+	}
+
+	if ok := assert.Equal(t, syscall.ENOENT, hosts.Getattr(context.Background(), nil, &out)); !ok {
+		t.Error()
+	}
+
+	_, _, errno = lookup(root, "etc/resolv.conf")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Error()
+	}
+
+	//the old node of motd is not forgotten, the directory needs a new inode
+	dir, _, errno := lookup(root, "etc/motd")
+	if ok := assert.Equal(t, fusefs.OK, errno); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.NotEqual(t, motd.StableAttr().Ino, dir.StableAttr().Ino); !ok {
+		t.Error()
+	}
+
+	if ok := assert.EqualValues(t, syscall.S_IFDIR, dir.StableAttr().Mode); !ok {
+		t.Error()
+	}
+}
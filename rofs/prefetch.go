@@ -0,0 +1,396 @@
+package rofs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/threefoldtech/0-fs/meta"
+	"github.com/threefoldtech/0-fs/storage"
+)
+
+const (
+	// DefaultPrefetchWorkers is the default number of blocks downloaded in parallel by a prefetch
+	DefaultPrefetchWorkers = 8
+)
+
+// Progress of a prefetch
+type Progress struct {
+	Files      int
+	TotalFiles int
+	Bytes      uint64
+	TotalBytes uint64
+	Errors     int
+}
+
+// PrefetchOption interface
+type PrefetchOption interface {
+	apply(p *Prefetcher)
+}
+
+type prefetchWorkersOpt struct {
+	workers uint
+}
+
+func (o prefetchWorkersOpt) apply(p *Prefetcher) {
+	p.workers = int(o.workers)
+}
+
+// WithPrefetchWorkers sets the number of blocks downloaded in parallel
+func WithPrefetchWorkers(nr uint) PrefetchOption {
+	return prefetchWorkersOpt{nr}
+}
+
+type patternsOpt struct {
+	patterns []string
+}
+
+func (o patternsOpt) apply(p *Prefetcher) {
+	p.patterns = append(p.patterns, o.patterns...)
+}
+
+// WithPatterns only prefetches the files that match one of the glob patterns (see path.Match).
+// A pattern that matches a directory matches all the files under this directory
+func WithPatterns(patterns ...string) PrefetchOption {
+	return patternsOpt{patterns}
+}
+
+type blockCacheOpt struct{}
+
+func (o blockCacheOpt) apply(p *Prefetcher) {
+	p.fs.SetBlockCache(true)
+}
+
+// WithBlockCache downloads the blocks into the block cache instead of the files cache
+// (see Config.SetBlockCache)
+func WithBlockCache() PrefetchOption {
+	return blockCacheOpt{}
+}
+
+type progressOpt struct {
+	fn func(Progress)
+}
+
+func (o progressOpt) apply(p *Prefetcher) {
+	p.progress = o.fn
+}
+
+// WithProgress sets a function that is called each time a file is processed
+func WithProgress(fn func(Progress)) PrefetchOption {
+	return progressOpt{fn}
+}
+
+/*
+Prefetcher downloads the files of a meta store into a cache directory, so they
+are available when the flist is mounted with the same cache. Blocks are downloaded
+in place using the same protocol as the mount, so a prefetch can run while the
+flist is mounted, and an interrupted prefetch resumes from the blocks that are
+already in the cache.
+*/
+type Prefetcher struct {
+	fs       *filesystem
+	workers  int
+	patterns []string
+	progress func(Progress)
+}
+
+type prefetchJob struct {
+	file  *prefetchFile
+	index int
+}
+
+type prefetchFile struct {
+	path    string
+	meta    meta.Meta
+	lazy    *lazyFile
+	blocks  *blockFile
+	pending int32
+	failed  int32
+}
+
+// NewPrefetcher creates a prefetcher of the files of store into the cache directory
+func NewPrefetcher(storage storage.Storage, store meta.Store, cache string, opts ...PrefetchOption) *Prefetcher {
+	p := &Prefetcher{
+		fs: &filesystem{
+			Config: NewConfig(storage, store, cache),
+		},
+		workers: DefaultPrefetchWorkers,
+	}
+
+	for _, opt := range opts {
+		opt.apply(p)
+	}
+
+	if p.workers <= 0 {
+		p.workers = DefaultPrefetchWorkers
+	}
+
+	return p
+}
+
+func (p *Prefetcher) match(name string) bool {
+	if len(p.patterns) == 0 {
+		return true
+	}
+
+	name = strings.Trim(name, "/")
+	for ; name != "." && name != "/" && len(name) != 0; name = path.Dir(name) {
+		for _, pattern := range p.patterns {
+			if ok, _ := path.Match(strings.Trim(pattern, "/"), name); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Walk prefetches all the files of the store that match the prefetcher patterns
+func (p *Prefetcher) Walk(ctx context.Context) error {
+	walker, ok := p.fs.getStore().(meta.Walker)
+	if !ok {
+		return fmt.Errorf("meta store can't be walked")
+	}
+
+	var files []*prefetchFile
+	err := walker.Walk("", func(name string, m meta.Meta) error {
+		if _, ok := CacheID(m); ok && p.match(name) {
+			files = append(files, &prefetchFile{path: name, meta: m})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return p.prefetch(ctx, files)
+}
+
+// Paths prefetches the given files in order, paths that are not regular files are ignored
+func (p *Prefetcher) Paths(ctx context.Context, paths []string) error {
+	var files []*prefetchFile
+	store := p.fs.getStore()
+	seen := make(map[string]struct{})
+	for _, name := range paths {
+		name = strings.Trim(name, "/")
+		if _, ok := seen[name]; ok {
+			continue
+		}
+
+		seen[name] = struct{}{}
+		m, ok := store.Get(name)
+		if !ok {
+			log.Warningf("prefetch: '%s' not found", name)
+			continue
+		}
+
+		if _, ok := CacheID(m); ok && p.match(name) {
+			files = append(files, &prefetchFile{path: name, meta: m})
+		}
+	}
+
+	return p.prefetch(ctx, files)
+}
+
+func (p *Prefetcher) prefetch(ctx context.Context, files []*prefetchFile) error {
+	progress := Progress{TotalFiles: len(files)}
+	for _, file := range files {
+		progress.TotalBytes += file.meta.Info().Size
+	}
+
+	var m sync.Mutex
+	done := func(file *prefetchFile) {
+		if file.lazy != nil {
+			file.lazy.Release()
+		}
+
+		m.Lock()
+		defer m.Unlock()
+
+		progress.Files++
+		progress.Bytes += file.meta.Info().Size
+		if atomic.LoadInt32(&file.failed) != 0 {
+			progress.Errors++
+		}
+
+		if p.progress != nil {
+			p.progress(progress)
+		}
+	}
+
+	jobs := make(chan prefetchJob)
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				p.work(job)
+				if atomic.AddInt32(&job.file.pending, -1) == 0 {
+					done(job.file)
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, file := range files {
+		if ctx.Err() != nil {
+			break
+		}
+
+		indexes, err := p.open(file)
+		if err != nil {
+			log.Errorf("prefetch: failed to open '%s': %s", file.path, err)
+			atomic.StoreInt32(&file.failed, 1)
+			done(file)
+			continue
+		}
+
+		if len(indexes) == 0 {
+			done(file)
+			continue
+		}
+
+		file.pending = int32(len(indexes))
+		for i, index := range indexes {
+			select {
+			case jobs <- prefetchJob{file: file, index: index}:
+			case <-ctx.Done():
+				//release the file once the queued blocks are done
+				if atomic.AddInt32(&file.pending, -int32(len(indexes)-i)) == 0 {
+					done(file)
+				}
+				break feed
+			}
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if progress.Errors != 0 {
+		return fmt.Errorf("failed to prefetch %d files", progress.Errors)
+	}
+
+	return nil
+}
+
+// open prepares the cache file of file, and returns the blocks that needs to be downloaded.
+// A block index of -1 means the full file must be downloaded
+func (p *Prefetcher) open(file *prefetchFile) ([]int, error) {
+	info := file.meta.Info()
+	if p.fs.blockCache && info.FileBlockSize != 0 {
+		var indexes []int
+		blocks := newBlockFile(p.fs, file.meta)
+		for index, block := range blocks.downloader.blocks {
+			if _, err := os.Stat(p.fs.blockPath(blockID(block))); os.IsNotExist(err) {
+				indexes = append(indexes, index)
+			}
+		}
+
+		file.blocks = blocks
+		return indexes, nil
+	}
+
+	name := p.fs.path(file.meta.ID())
+	f, err := p.fs.lock(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fstat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if cached(name, fstat.Size(), info) {
+		f.Close()
+		return nil, nil
+	}
+
+	if info.FileBlockSize == 0 {
+		f.Close()
+		return []int{-1}, nil
+	}
+
+	lazy, err := newLazyFile(f, NewDownloader(p.fs.storage, file.meta), int64(info.Size))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	lazy.downloads = &p.fs.blocks
+
+	var indexes []int
+	lazy.m.Lock()
+	for index := range lazy.downloader.blocks {
+		if !lazy.has(index) {
+			indexes = append(indexes, index)
+		}
+	}
+	lazy.m.Unlock()
+
+	if len(indexes) == 0 {
+		lazy.Release()
+		return nil, nil
+	}
+
+	file.lazy = lazy
+	return indexes, nil
+}
+
+func (p *Prefetcher) work(job prefetchJob) {
+	var err error
+	if job.index < 0 {
+		var f *os.File
+		f, err = p.fs.checkAndGet(job.file.meta)
+		if err == nil {
+			f.Close()
+		}
+	} else if job.file.blocks != nil {
+		var f *os.File
+		f, err = job.file.blocks.block(job.index)
+		if err == nil {
+			f.Close()
+		}
+	} else {
+		err = job.file.lazy.fetch(job.index)
+	}
+
+	if err != nil {
+		log.Errorf("prefetch: failed to download '%s': %s", job.file.path, err)
+		atomic.StoreInt32(&job.file.failed, 1)
+	}
+}
+
+// ReadAccessList reads a list of paths, one per line. Empty lines and lines starting
+// with # are ignored. If a line has multiple tab separated fields, the path is the last field
+func ReadAccessList(r io.Reader) ([]string, error) {
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		paths = append(paths, fields[len(fields)-1])
+	}
+
+	return paths, scanner.Err()
+}
@@ -0,0 +1,144 @@
+package rofs
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/0-fs/meta"
+	"github.com/threefoldtech/0-fs/meta/writer"
+)
+
+func makeFlist(t *testing.T, files ...string) (meta.Store, putStorage, func()) {
+	src, err := ioutil.TempDir("", "prefetch-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	for _, name := range files {
+		content := make([]byte, 10*1024)
+		rand.Read(content)
+		name = filepath.Join(src, name)
+		os.MkdirAll(filepath.Dir(name), 0755)
+		if ok := assert.NoError(t, ioutil.WriteFile(name, content, 0644)); !ok {
+			t.Fatal()
+		}
+	}
+
+	storage := putStorage{}
+	store, clean := writeFlist(t, src, storage, writer.WithBlockSize(4))
+	return store, storage, clean
+}
+
+func isCached(t *testing.T, fs *filesystem, name string) bool {
+	m, ok := fs.getStore().Get(name)
+	if ok := assert.True(t, ok, name); !ok {
+		t.Fatal()
+	}
+
+	stat, err := os.Stat(fs.path(m.ID()))
+	if err != nil {
+		return false
+	}
+
+	return cached(fs.path(m.ID()), stat.Size(), m.Info())
+}
+
+func TestPrefetchWalk(t *testing.T) {
+	store, storage, clean := makeFlist(t, "bin/a", "bin/b", "lib/c", "etc/d")
+	defer clean()
+
+	cache, err := ioutil.TempDir("", "prefetch-cache-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(cache)
+
+	var last Progress
+	prefetcher := NewPrefetcher(storage, store, cache,
+		WithPatterns("bin", "*/c"),
+		WithProgress(func(p Progress) { last = p }),
+	)
+
+	if ok := assert.NoError(t, prefetcher.Walk(context.Background())); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, Progress{Files: 3, TotalFiles: 3, Bytes: 3 * 10 * 1024, TotalBytes: 3 * 10 * 1024}, last); !ok {
+		t.Error()
+	}
+
+	for name, expected := range map[string]bool{
+		"bin/a": true,
+		"bin/b": true,
+		"lib/c": true,
+		"etc/d": false,
+	} {
+		if ok := assert.Equal(t, expected, isCached(t, prefetcher.fs, name), name); !ok {
+			t.Error()
+		}
+	}
+}
+
+func TestPrefetchResume(t *testing.T) {
+	store, storage, clean := makeFlist(t, "a", "b")
+	defer clean()
+
+	cache, err := ioutil.TempDir("", "prefetch-cache-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(cache)
+
+	//drop the last block of b
+	b, _ := store.Get("b")
+	blocks := b.Blocks()
+	key := string(blocks[len(blocks)-1].Key)
+	data := storage[key]
+	delete(storage, key)
+
+	paths, err := ReadAccessList(strings.NewReader("# trace\n1\topen\t/b\n\n2\topen\t/a\n"))
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, []string{"/b", "/a"}, paths); !ok {
+		t.Error()
+	}
+
+	prefetcher := NewPrefetcher(storage, store, cache)
+	if ok := assert.Error(t, prefetcher.Paths(context.Background(), paths)); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.True(t, isCached(t, prefetcher.fs, "a")); !ok {
+		t.Error()
+	}
+
+	if ok := assert.False(t, isCached(t, prefetcher.fs, "b")); !ok {
+		t.Error()
+	}
+
+	//only the missing block is downloaded again
+	for _, block := range blocks[:len(blocks)-1] {
+		delete(storage, string(block.Key))
+	}
+	storage[key] = data
+
+	if ok := assert.NoError(t, prefetcher.Paths(context.Background(), paths)); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.True(t, isCached(t, prefetcher.fs, "b")); !ok {
+		t.Error()
+	}
+}
@@ -2,7 +2,10 @@ package rofs
 
 import (
 	"fmt"
-	"syscall"
+	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
 	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
@@ -10,6 +13,7 @@ import (
 	"github.com/op/go-logging"
 	"github.com/threefoldtech/0-fs/meta"
 	"github.com/threefoldtech/0-fs/storage"
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -24,14 +28,89 @@ var (
 // Configuration objects can be used to manipulate some filesystem flags in runtime
 type Config struct {
 	store   meta.Store
+	sm      sync.RWMutex
 	storage storage.Storage
 	cache   string
+	manager *CacheManager
+	tracer  *Recorder
+
+	verify   bool
+	verified map[string]struct{}
+	vm       sync.Mutex
+
+	blockCache bool
+	blocks     blockDownloads
+
+	usage storeUsage
+
+	//generation is incremented each time the store is changed
+	generation uint64
 }
 
 //SetMetaStore sets the filesystem meta store in runtime.
 func (c *Config) SetMetaStore(store meta.Store) {
-	//TODO: should this be done atomically in a way that is synched ?
+	c.sm.Lock()
+	defer c.sm.Unlock()
+
 	c.store = store
+	c.usage.update(store)
+	atomic.AddUint64(&c.generation, 1)
+}
+
+//getStore returns the current meta store, it must be used to read the store
+//since it can be changed in runtime by SetMetaStore
+func (c *Config) getStore() meta.Store {
+	c.sm.RLock()
+	defer c.sm.RUnlock()
+
+	return c.store
+}
+
+//SetCacheManager sets the manager that keeps the cache directory size under a budget
+func (c *Config) SetCacheManager(manager *CacheManager) {
+	c.manager = manager
+}
+
+//SetBlockCache enables the block cache. Files are served from a cache of blocks
+//where each block is stored once, instead of keeping a copy of each file
+func (c *Config) SetBlockCache(enabled bool) {
+	c.blockCache = enabled
+}
+
+//SetVerify enables the verification of cache files the first time they are opened.
+//Corrupt blocks are downloaded again
+func (c *Config) SetVerify(verify bool) {
+	c.vm.Lock()
+	defer c.vm.Unlock()
+
+	c.verify = verify
+	c.verified = make(map[string]struct{})
+}
+
+//shouldVerify returns true if verification is enabled and id was not verified yet
+func (c *Config) shouldVerify(id string) bool {
+	c.vm.Lock()
+	defer c.vm.Unlock()
+
+	if !c.verify {
+		return false
+	}
+
+	_, ok := c.verified[id]
+	return !ok
+}
+
+//setVerified records that the cache file of id was verified successfully
+func (c *Config) setVerified(id string) {
+	c.vm.Lock()
+	defer c.vm.Unlock()
+
+	c.verified[id] = struct{}{}
+}
+
+//SetRecorder sets the recorder of the files access trace
+func (c *Config) SetRecorder(recorder *Recorder) {
+	c.tracer = recorder
 }
 
 //SetDataStorage sets the filesystem data storage in runtime
@@ -66,7 +145,13 @@ func New(cfg *Config) pathfs.FileSystem {
 
 func (fs *filesystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
 	log.Debugf("GetAttr %s", name)
-	m, ok := fs.store.Get(name)
+	defer observe("getattr")()
+
+	return fs.getAttr(name)
+}
+
+func (fs *filesystem) getAttr(name string) (*fuse.Attr, fuse.Status) {
+	m, ok := fs.getStore().Get(name)
 	if !ok {
 		return nil, fuse.ENOENT
 	}
@@ -76,17 +161,17 @@ func (fs *filesystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, f
 		return nil, fuse.EIO
 	}
 
-	var ino uint64 = 0
-
-	if info.Type == meta.RegularType {
-		stat, err := fs.check(m)
-		if err != nil {
-			return nil, fuse.EIO
-		}
+	return attr(name, info), fuse.OK
+}
 
-		if stat, ok := stat.Sys().(*syscall.Stat_t); ok && stat != nil {
-			ino = stat.Ino
-		}
+// attr returns the attributes of the entry at name
+func attr(name string, info meta.Info) *fuse.Attr {
+	//only hard links have a link count in the flist
+	nlink := uint32(1)
+	if info.InodeID != 0 {
+		nlink = info.NLink
+	} else if info.Type == meta.DirType {
+		nlink = 2
 	}
 
 	nodeType := uint32(info.Type)
@@ -109,7 +194,8 @@ func (fs *filesystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, f
 	// log.Debugf("owner: uid %v gid %v", access.UID, access.GID)
 
 	return &fuse.Attr{
-		Ino:    ino,
+		Ino:    inode(name, info),
+		Nlink:  nlink,
 		Size:   size,
 		Atime:  uint64(info.ModificationTime),
 		Mtime:  uint64(info.ModificationTime),
@@ -120,37 +206,60 @@ func (fs *filesystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, f
 			Uid: access.UID,
 			Gid: access.GID,
 		},
-		Rdev:    major<<8 | minor,
+		Rdev:    uint32(unix.Mkdev(major, minor)),
 		Blksize: blkSize, //4K blocks
-	}, fuse.OK
+	}
+}
+
+// inode returns the inode number of the entry at name. It only depends on the
+// flist, so it is the same across mounts and doesn't need the file to be cached
+func inode(name string, info meta.Info) uint64 {
+	if info.InodeID != 0 {
+		//hard links share the same inode
+		return info.InodeID
+	}
+
+	//the type is mixed in the number, so an entry that changes type in a new
+	//version of the flist doesn't reuse the inode of the old entry
+	ino := meta.InodeNumber(name) ^ uint64(info.Type)
+	if ino == 0 {
+		ino = 1
+	}
+
+	return ino
 }
 
 func (fs *filesystem) Open(name string, flags uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
 	log.Debugf("Open %s", name)
+	defer observe("open")()
+
 	if flags&fuse.O_ANYWRITE != 0 {
 		return nil, fuse.EPERM
 	}
-	m, ok := fs.store.Get(name)
+	m, ok := fs.getStore().Get(name)
 	if !ok {
 		return nil, fuse.ENOENT
 	}
-	f, err := fs.checkAndGet(m)
+	fs.tracer.Record(TraceOpen, name)
+	f, err := fs.open(m)
 	if err != nil {
 		log.Errorf("Failed to open/download the file: %s", err)
+		return nil, fuse.EIO
 	}
 
 	// fetch original attr and store them to reuse
 	// for fd in cache later (no new GetAttr will be done
 	// if the file is already open and it will forward
 	// local cache file attrs)
-	attr, ferr := fs.GetAttr(name, context)
+	attr, ferr := fs.getAttr(name)
 	if ferr != fuse.OK {
 		log.Errorf("Failed to fetch original attr: %s", ferr)
+		f.Release()
 		return nil, ferr
 	}
 
 	file := &WithAttr{
-		File:   nodefs.NewLoopbackFile(f),
+		File:   f,
 		Source: attr,
 	}
 
@@ -159,21 +268,36 @@ func (fs *filesystem) Open(name string, flags uint32, context *fuse.Context) (no
 
 func (fs *filesystem) OpenDir(name string, context *fuse.Context) ([]fuse.DirEntry, fuse.Status) {
 	log.Debugf("OpenDir %s", name)
-	m, ok := fs.store.Get(name)
+	defer observe("opendir")()
+
+	m, ok := fs.getStore().Get(name)
 	if !ok {
 		return nil, fuse.ENOENT
 	}
+	fs.tracer.Record(TraceOpenDir, name)
+
+	return dirEntries(name, m), fuse.OK
+}
+
+// dirEntries returns the entries of the directory m at name
+func dirEntries(name string, m meta.Meta) []fuse.DirEntry {
 	var entries []fuse.DirEntry
 	for _, child := range m.Children() {
 		info := child.Info()
 		log.Debugf("child '%s', type: %s", child.Name(), info.Type)
+		if info.Type == meta.UnknownType {
+			//can't be stat'ed
+			continue
+		}
+
 		entries = append(entries, fuse.DirEntry{
 			Mode: uint32(info.Type),
 			Name: child.Name(),
+			Ino:  inode(path.Join(name, child.Name()), info),
 		})
 	}
 
-	return entries, fuse.OK
+	return entries
 }
 
 func (fs *filesystem) String() string {
@@ -186,10 +310,13 @@ func (fs *filesystem) Access(name string, mode uint32, context *fuse.Context) fu
 
 func (fs *filesystem) Readlink(name string, context *fuse.Context) (string, fuse.Status) {
 	log.Debugf("Readlink %s", name)
-	m, ok := fs.store.Get(name)
+	defer observe("readlink")()
+
+	m, ok := fs.getStore().Get(name)
 	if !ok {
 		return "", fuse.ENOENT
 	}
+	fs.tracer.Record(TraceReadlink, name)
 
 	info := m.Info()
 
@@ -197,17 +324,44 @@ func (fs *filesystem) Readlink(name string, context *fuse.Context) (string, fuse
 }
 
 func (fs *filesystem) GetXAttr(name string, attr string, context *fuse.Context) ([]byte, fuse.Status) {
-	log.Debugf("GetXAttr %s", name)
-	return nil, fuse.ENOSYS
+	log.Debugf("GetXAttr %s %s", name, attr)
+	defer observe("getxattr")()
+
+	m, ok := fs.getStore().Get(name)
+	if !ok {
+		return nil, fuse.ENOENT
+	}
+
+	value, ok := m.XAttrs()[attr]
+	if !ok {
+		return nil, fuse.ENOATTR
+	}
+
+	return value, fuse.OK
 }
 
 func (fs *filesystem) ListXAttr(name string, context *fuse.Context) ([]string, fuse.Status) {
 	log.Debugf("ListXAttr %s", name)
-	return nil, fuse.ENOSYS
+	defer observe("listxattr")()
+
+	m, ok := fs.getStore().Get(name)
+	if !ok {
+		return nil, fuse.ENOENT
+	}
+
+	return xattrNames(m), fuse.OK
 }
 
-func (fs *filesystem) StatFs(name string) *fuse.StatfsOut {
-	return &fuse.StatfsOut{}
+// xattrNames returns the sorted names of the extended attributes of m
+func xattrNames(m meta.Meta) []string {
+	xattrs := m.XAttrs()
+	names := make([]string, 0, len(xattrs))
+	for name := range xattrs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
 }
 
 // WithAttr override nodefs.File with custom GetAttr
@@ -0,0 +1,409 @@
+package rofs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/0-fs/meta"
+	"github.com/threefoldtech/0-fs/meta/writer"
+	"golang.org/x/sys/unix"
+)
+
+// writeFlist creates an flist of the src directory, the files blocks are stored in storage
+func writeFlist(t *testing.T, src string, storage putStorage, opts ...writer.Option) (meta.Store, func()) {
+	dst, err := ioutil.TempDir("", "flist-dst-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	w, err := writer.New(dst, storage, opts...)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.NoError(t, w.Add(src)); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.NoError(t, w.Close()); !ok {
+		t.Fatal()
+	}
+
+	store, err := meta.NewStore(dst)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dst)
+	}
+}
+
+func TestXAttr(t *testing.T) {
+	src, err := ioutil.TempDir("", "xattr-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	ping := filepath.Join(src, "bin", "ping")
+	os.MkdirAll(filepath.Dir(ping), 0755)
+	if ok := assert.NoError(t, ioutil.WriteFile(ping, []byte("ping"), 0755)); !ok {
+		t.Fatal()
+	}
+
+	//cap_net_raw+ep
+	capability := []byte{0, 0, 0, 2, 0, 32, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if err := unix.Setxattr(ping, "security.capability", capability, 0); err == unix.ENOTSUP || err == unix.EPERM {
+		t.Skipf("can't set file capabilities: %s", err)
+	} else if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	store, clean := writeFlist(t, src, putStorage{})
+	defer clean()
+
+	fs := &filesystem{Config: NewConfig(putStorage{}, store, os.TempDir())}
+
+	attrs, status := fs.ListXAttr("bin/ping", nil)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, []string{"security.capability"}, attrs); !ok {
+		t.Error()
+	}
+
+	value, status := fs.GetXAttr("bin/ping", "security.capability", nil)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, capability, value); !ok {
+		t.Error()
+	}
+
+	_, status = fs.GetXAttr("bin/ping", "user.missing", nil)
+	if ok := assert.Equal(t, fuse.ENOATTR, status); !ok {
+		t.Error()
+	}
+
+	attrs, status = fs.ListXAttr("bin", nil)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Empty(t, attrs); !ok {
+		t.Error()
+	}
+
+	_, status = fs.ListXAttr("bin/missing", nil)
+	if ok := assert.Equal(t, fuse.ENOENT, status); !ok {
+		t.Error()
+	}
+}
+
+func TestSpecial(t *testing.T) {
+	src, err := ioutil.TempDir("", "special-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	dev := filepath.Join(src, "dev")
+	os.MkdirAll(dev, 0755)
+	if ok := assert.NoError(t, unix.Mkfifo(filepath.Join(dev, "fifo"), 0620)); !ok {
+		t.Fatal()
+	}
+
+	//a minor above 255 needs the new encoding of the device number
+	devices := map[string]struct {
+		mode         uint32
+		major, minor uint32
+	}{
+		"null":  {unix.S_IFCHR | 0666, 1, 3},
+		"loop0": {unix.S_IFBLK | 0660, 7, 0},
+		"ttyS":  {unix.S_IFCHR | 0660, 4, 300},
+	}
+
+	for name, device := range devices {
+		err := unix.Mknod(filepath.Join(dev, name), device.mode, int(unix.Mkdev(device.major, device.minor)))
+		if err == unix.EPERM {
+			t.Logf("can't create device nodes: %s", err)
+			devices = nil
+			break
+		} else if ok := assert.NoError(t, err); !ok {
+			t.Fatal()
+		}
+	}
+
+	store, clean := writeFlist(t, src, putStorage{})
+	defer clean()
+
+	fs := &filesystem{Config: NewConfig(putStorage{}, store, os.TempDir())}
+
+	entries, status := fs.OpenDir("dev", nil)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	listed := make(map[string]uint32)
+	for _, entry := range entries {
+		listed[entry.Name] = entry.Mode
+	}
+
+	expected := map[string]uint32{"fifo": unix.S_IFIFO}
+	for name, device := range devices {
+		expected[name] = device.mode & unix.S_IFMT
+	}
+
+	if ok := assert.Equal(t, expected, listed); !ok {
+		t.Error()
+	}
+
+	attr, status := fs.GetAttr("dev/fifo", nil)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	//modes are compared to the source since they are subject to umask
+	var stat unix.Stat_t
+	unix.Lstat(filepath.Join(dev, "fifo"), &stat)
+	if ok := assert.Equal(t, stat.Mode, attr.Mode); !ok {
+		t.Error()
+	}
+
+	for name, device := range devices {
+		attr, status := fs.GetAttr(filepath.Join("dev", name), nil)
+		if ok := assert.Equal(t, fuse.OK, status, name); !ok {
+			t.Fatal()
+		}
+
+		unix.Lstat(filepath.Join(dev, name), &stat)
+		if ok := assert.Equal(t, stat.Mode, attr.Mode, name); !ok {
+			t.Error()
+		}
+
+		if ok := assert.Equal(t, device.major, unix.Major(uint64(attr.Rdev)), name); !ok {
+			t.Error()
+		}
+
+		if ok := assert.Equal(t, device.minor, unix.Minor(uint64(attr.Rdev)), name); !ok {
+			t.Error()
+		}
+	}
+}
+
+func TestHardlink(t *testing.T) {
+	src, err := ioutil.TempDir("", "hardlink-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	bin := filepath.Join(src, "bin")
+	os.MkdirAll(bin, 0755)
+	if ok := assert.NoError(t, ioutil.WriteFile(filepath.Join(bin, "busybox"), []byte("busybox"), 0755)); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.NoError(t, os.Link(filepath.Join(bin, "busybox"), filepath.Join(bin, "sh"))); !ok {
+		t.Fatal()
+	}
+
+	store, clean := writeFlist(t, src, putStorage{})
+	defer clean()
+
+	fs := &filesystem{Config: NewConfig(putStorage{}, store, os.TempDir())}
+
+	busybox, status := fs.GetAttr("bin/busybox", nil)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	sh, status := fs.GetAttr("bin/sh", nil)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.NotZero(t, busybox.Ino); !ok {
+		t.Error()
+	}
+
+	if ok := assert.Equal(t, busybox.Ino, sh.Ino); !ok {
+		t.Error()
+	}
+
+	if ok := assert.EqualValues(t, 2, sh.Nlink); !ok {
+		t.Error()
+	}
+
+	//both links are backed by the same cache file
+	m1, _ := store.Get("bin/busybox")
+	m2, _ := store.Get("bin/sh")
+	if ok := assert.Equal(t, fs.path(m1.ID()), fs.path(m2.ID())); !ok {
+		t.Error()
+	}
+}
+
+func TestInode(t *testing.T) {
+	src, err := ioutil.TempDir("", "inode-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	os.MkdirAll(filepath.Join(src, "etc", "ssl"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "etc", "hostname"), []byte("zos"), 0644)
+	ioutil.WriteFile(filepath.Join(src, "etc", "hosts"), []byte("127.0.0.1 localhost"), 0644)
+	os.Symlink("hostname", filepath.Join(src, "etc", "name"))
+
+	store, clean := writeFlist(t, src, putStorage{})
+	defer clean()
+
+	cache, err := ioutil.TempDir("", "inode-cache-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(cache)
+
+	fs := &filesystem{Config: NewConfig(putStorage{}, store, cache)}
+	//a second mount of the same flist
+	other := &filesystem{Config: NewConfig(putStorage{}, store, os.TempDir())}
+
+	inodes := make(map[uint64]string)
+	for _, name := range []string{"", "etc", "etc/ssl", "etc/hostname", "etc/hosts", "etc/name"} {
+		attr, status := fs.GetAttr(name, nil)
+		if ok := assert.Equal(t, fuse.OK, status); !ok {
+			t.Fatal()
+		}
+
+		if ok := assert.NotZero(t, attr.Ino, name); !ok {
+			t.Error()
+		}
+
+		if ok := assert.NotContains(t, inodes, attr.Ino, name); !ok {
+			t.Error()
+		}
+
+		inodes[attr.Ino] = name
+
+		nlink := uint32(1)
+		if name == "" || name == "etc" || name == "etc/ssl" {
+			nlink = 2
+		}
+
+		if ok := assert.Equal(t, nlink, attr.Nlink, name); !ok {
+			t.Error()
+		}
+
+		again, _ := other.GetAttr(name, nil)
+		if ok := assert.Equal(t, attr.Ino, again.Ino, name); !ok {
+			t.Error()
+		}
+	}
+
+	entries, status := fs.OpenDir("etc", nil)
+	if ok := assert.Equal(t, fuse.OK, status); !ok {
+		t.Fatal()
+	}
+
+	for _, entry := range entries {
+		if ok := assert.Equal(t, "etc/"+entry.Name, inodes[entry.Ino]); !ok {
+			t.Error()
+		}
+	}
+
+	//stat doesn't need the files to be cached
+	files, _ := ioutil.ReadDir(cache)
+	if ok := assert.Empty(t, files); !ok {
+		t.Error()
+	}
+}
+
+func TestStatFs(t *testing.T) {
+	src, err := ioutil.TempDir("", "statfs-src-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(src)
+
+	os.MkdirAll(filepath.Join(src, "etc"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "etc", "hostname"), []byte("zos"), 0644)
+	ioutil.WriteFile(filepath.Join(src, "etc", "data"), make([]byte, 5000), 0644)
+	os.Symlink("hostname", filepath.Join(src, "etc", "name"))
+
+	store, clean := writeFlist(t, src, putStorage{})
+	defer clean()
+
+	fs := &filesystem{Config: NewConfig(putStorage{}, store, os.TempDir())}
+
+	//usage is not known before the store is walked
+	out := fs.StatFs("")
+	if ok := assert.NotNil(t, out); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.True(t, waitUsage(fs)); !ok {
+		t.Fatal()
+	}
+
+	out = fs.StatFs("")
+
+	//one block for hostname and 2 for data
+	if ok := assert.EqualValues(t, 3, out.Blocks-out.Bfree); !ok {
+		t.Error()
+	}
+
+	//root, etc, hostname, data and name
+	if ok := assert.EqualValues(t, 5, out.Files-out.Ffree); !ok {
+		t.Error()
+	}
+
+	if ok := assert.EqualValues(t, blkSize, out.Bsize); !ok {
+		t.Error()
+	}
+
+	//usage is computed again for a new store, the last known usage is reported meanwhile
+	fs.SetMetaStore(store)
+	if ok := assert.True(t, waitUsage(fs)); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.EqualValues(t, 2, fs.usage.done); !ok {
+		t.Error()
+	}
+
+	if ok := assert.EqualValues(t, 5, fs.usage.stats.files); !ok {
+		t.Error()
+	}
+}
+
+//waitUsage waits for the last started walk of the store to be done
+func waitUsage(fs *filesystem) bool {
+	for i := 0; i < 100; i++ {
+		fs.usage.m.Lock()
+		done := fs.usage.done == fs.usage.gen
+		fs.usage.m.Unlock()
+
+		if done {
+			return true
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return false
+}
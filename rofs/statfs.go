@@ -0,0 +1,119 @@
+package rofs
+
+import (
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/threefoldtech/0-fs/meta"
+)
+
+const (
+	nameLen = 255
+)
+
+// usageStats is the space used by the files of a meta store
+type usageStats struct {
+	blocks uint64
+	files  uint64
+}
+
+// storeUsage computes the usage of a store in the background, so StatFs never
+// walks the store itself and reports the last known usage instead
+type storeUsage struct {
+	stats   usageStats
+	started bool
+	//gen is incremented each time a walk is started, done is the generation of stats
+	gen  uint64
+	done uint64
+	m    sync.Mutex
+}
+
+// update starts computing the usage of store, the result of a walk is dropped if a
+// newer one was started in the meantime
+func (u *storeUsage) update(store meta.Store) {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	u.start(store)
+}
+
+// start must be called with the lock held
+func (u *storeUsage) start(store meta.Store) {
+	u.started = true
+	u.gen++
+
+	go u.walk(u.gen, store)
+}
+
+func (u *storeUsage) walk(gen uint64, store meta.Store) {
+	var stats usageStats
+	if walker, ok := store.(meta.Walker); ok {
+		err := walker.Walk("", func(path string, m meta.Meta) error {
+			stats.files++
+
+			info := m.Info()
+			if info.Type == meta.RegularType {
+				stats.blocks += (info.Size + blkSize - 1) / blkSize
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			log.Errorf("failed to compute flist usage: %s", err)
+			return
+		}
+	}
+
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	if gen < u.gen {
+		return
+	}
+
+	u.stats = stats
+	u.done = gen
+}
+
+// get returns the last known usage, the first call starts computing the usage of
+// store if it was not started by SetMetaStore
+func (u *storeUsage) get(store meta.Store) usageStats {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	if !u.started {
+		u.start(store)
+	}
+
+	return u.stats
+}
+
+// StatFs reports the files of the flist as the used space, and the free space of
+// the cache directory as the free space
+func (fs *filesystem) StatFs(name string) *fuse.StatfsOut {
+	log.Debugf("StatFs %s", name)
+	defer observe("statfs")()
+
+	//usage is zero until the first walk of the store is done
+	used := fs.usage.get(fs.getStore())
+
+	var cache syscall.Statfs_t
+	if err := syscall.Statfs(fs.cache, &cache); err != nil {
+		log.Errorf("failed to get cache directory (%s) usage: %s", fs.cache, err)
+	}
+
+	free := cache.Bavail * uint64(cache.Bsize) / blkSize
+
+	return &fuse.StatfsOut{
+		Blocks:  used.blocks + free,
+		Bfree:   free,
+		Bavail:  free,
+		Files:   used.files + cache.Ffree,
+		Ffree:   cache.Ffree,
+		Bsize:   blkSize,
+		NameLen: nameLen,
+		Frsize:  blkSize,
+	}
+}
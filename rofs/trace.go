@@ -0,0 +1,81 @@
+package rofs
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Operations recorded by a Recorder
+const (
+	TraceOpen     = "open"
+	TraceReadlink = "readlink"
+	TraceOpenDir  = "opendir"
+)
+
+/*
+Recorder records the first access of each path of the filesystem. Each access is
+written as a line to the trace file
+
+	<timestamp>\t<operation>\t/<path>
+
+The timestamp is in RFC3339 format with nanoseconds. A trace can be read back with
+ReadAccessList to prefetch the files in the same order.
+*/
+type Recorder struct {
+	file *os.File
+	seen map[string]struct{}
+
+	m sync.Mutex
+}
+
+// NewRecorder creates a recorder that writes the trace to file name, the file is truncated
+func NewRecorder(name string) (*Recorder, error) {
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		file: file,
+		seen: make(map[string]struct{}),
+	}, nil
+}
+
+// Record records an access to name, only the first access of each path is recorded
+func (r *Recorder) Record(operation, name string) {
+	if r == nil {
+		return
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if _, ok := r.seen[name]; ok {
+		return
+	}
+
+	r.seen[name] = struct{}{}
+	if r.file == nil {
+		return
+	}
+
+	if _, err := fmt.Fprintf(r.file, "%s\t%s\t/%s\n", time.Now().Format(time.RFC3339Nano), operation, name); err != nil {
+		log.Errorf("failed to record access trace: %s", err)
+	}
+}
+
+// Close closes the trace file
+func (r *Recorder) Close() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
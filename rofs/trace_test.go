@@ -0,0 +1,61 @@
+package rofs
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	out, err := ioutil.TempFile("", "trace-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	out.Close()
+	defer os.Remove(out.Name())
+
+	recorder, err := NewRecorder(out.Name())
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	recorder.Record(TraceOpenDir, "")
+	recorder.Record(TraceOpen, "bin/sh")
+	recorder.Record(TraceReadlink, "lib")
+	recorder.Record(TraceOpen, "bin/sh")
+	recorder.Record(TraceOpen, "etc/passwd")
+
+	if ok := assert.NoError(t, recorder.Close()); !ok {
+		t.Fatal()
+	}
+
+	//a closed recorder ignores new accesses
+	recorder.Record(TraceOpen, "etc/hosts")
+
+	data, err := ioutil.ReadFile(out.Name())
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if ok := assert.Len(t, lines, 4); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.True(t, strings.HasSuffix(lines[1], "\topen\t/bin/sh")); !ok {
+		t.Error()
+	}
+
+	paths, err := ReadAccessList(strings.NewReader(string(data)))
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, []string{"/", "/bin/sh", "/lib", "/etc/passwd"}, paths); !ok {
+		t.Error()
+	}
+}
@@ -0,0 +1,208 @@
+package rofs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/threefoldtech/0-fs/meta"
+	"golang.org/x/crypto/blake2b"
+)
+
+// blockHash returns the hash of a decrypted block, it must match the block decipher
+func blockHash(data []byte) ([]byte, error) {
+	hasher, err := blake2b.New(16, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := hasher.Write(data); err != nil {
+		return nil, err
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+// verifyBlocks checks the blocks of file for which present returns true, and returns
+// the indexes of the corrupt blocks
+func verifyBlocks(file *os.File, m meta.Meta, present func(int) bool) ([]int, error) {
+	info := m.Info()
+	blockSize := int64(info.FileBlockSize)
+	size := int64(info.Size)
+
+	var corrupt []int
+	buf := make([]byte, blockSize)
+	for index, block := range m.Blocks() {
+		if !present(index) {
+			continue
+		}
+
+		off := int64(index) * blockSize
+		length := blockSize
+		if off+length > size {
+			length = size - off
+		}
+
+		n, err := file.ReadAt(buf[:length], off)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		hash, err := blockHash(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+
+		if int64(n) != length || !bytes.Equal(hash, block.Decipher) {
+			log.Warningf("block %d of %s is corrupt", index, file.Name())
+			corrupt = append(corrupt, index)
+		}
+	}
+
+	cacheCorruptBlocks.Add(float64(len(corrupt)))
+	return corrupt, nil
+}
+
+/*
+verify checks the blocks of the (locked) cache file f of m. Only the blocks that
+are in the cache are checked, so a partially downloaded file can be verified.
+If repair is set, the corrupt blocks are marked as missing in the blocks bitmap of
+the file so they are downloaded again on the next read.
+
+Files with no block size can't be verified, they are reported as valid.
+*/
+func verify(f *os.File, m meta.Meta, repair bool) ([]int, error) {
+	info := m.Info()
+	if info.FileBlockSize == 0 || len(m.Blocks()) == 0 {
+		return nil, nil
+	}
+
+	name := f.Name()
+	bitmapFile, err := os.OpenFile(bitmapPath(name), os.O_RDWR, 0644)
+	if os.IsNotExist(err) {
+		fstat, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		if fstat.Size() != int64(info.Size) {
+			//not downloaded yet
+			return nil, nil
+		}
+
+		//complete file, a bitmap is only created if the file needs repair
+		return verifyComplete(f, m, repair)
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer bitmapFile.Close()
+	if err := syscall.Flock(int(bitmapFile.Fd()), syscall.LOCK_EX); err != nil {
+		return nil, err
+	}
+
+	bitmap := make([]byte, (len(m.Blocks())+7)/8)
+	if _, err := bitmapFile.ReadAt(bitmap, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	corrupt, err := verifyBlocks(f, m, func(index int) bool {
+		return bitmap[index/8]&(1<<uint(index%8)) != 0
+	})
+
+	if err != nil || len(corrupt) == 0 || !repair {
+		return corrupt, err
+	}
+
+	for _, index := range corrupt {
+		bitmap[index/8] &^= 1 << uint(index%8)
+	}
+
+	_, err = bitmapFile.WriteAt(bitmap, 0)
+	return corrupt, err
+}
+
+func verifyComplete(f *os.File, m meta.Meta, repair bool) ([]int, error) {
+	corrupt, err := verifyBlocks(f, m, func(int) bool { return true })
+	if err != nil || len(corrupt) == 0 || !repair {
+		return corrupt, err
+	}
+
+	bitmap := make([]byte, (len(m.Blocks())+7)/8)
+	for index := range m.Blocks() {
+		bitmap[index/8] |= 1 << uint(index%8)
+	}
+
+	for _, index := range corrupt {
+		bitmap[index/8] &^= 1 << uint(index%8)
+	}
+
+	bitmapFile, err := os.OpenFile(bitmapPath(f.Name()), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if os.IsExist(err) {
+		//someone else is repairing or downloading the file
+		return corrupt, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer bitmapFile.Close()
+	if _, err := bitmapFile.Write(bitmap); err != nil {
+		return nil, err
+	}
+
+	return corrupt, nil
+}
+
+// VerifyResult of a cache file verification
+type VerifyResult struct {
+	//Path of the file in the flist
+	Path string
+	//Cached is true if the file has (some) blocks in the cache
+	Cached bool
+	//Corrupt blocks indexes
+	Corrupt []int
+}
+
+/*
+Verify checks the cache files of all files in store against the block hashes of
+the flist. If repair is set, corrupt blocks are marked as missing so they are
+downloaded again on the next read (or prefetch). fn is called for each file
+*/
+func Verify(store meta.Store, cache string, repair bool, fn func(VerifyResult)) error {
+	walker, ok := store.(meta.Walker)
+	if !ok {
+		return fmt.Errorf("meta store can't be walked")
+	}
+
+	fs := &filesystem{Config: NewConfig(nil, store, cache)}
+	return walker.Walk("", func(path string, m meta.Meta) error {
+		id, ok := CacheID(m)
+		if !ok {
+			return nil
+		}
+
+		result := VerifyResult{Path: path}
+		name := fs.path(id)
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			fn(result)
+			return nil
+		}
+
+		f, err := fs.lock(name)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+		result.Cached = true
+		result.Corrupt, err = verify(f, m, repair)
+		if err != nil {
+			return err
+		}
+
+		fn(result)
+		return nil
+	})
+}
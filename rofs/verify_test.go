@@ -0,0 +1,140 @@
+package rofs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func corruptBlock(t *testing.T, fs *filesystem, name string, index int) {
+	m, _ := fs.getStore().Get(name)
+	f, err := os.OpenFile(fs.path(m.ID()), os.O_RDWR, 0)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer f.Close()
+
+	_, err = f.WriteAt([]byte("corrupt"), int64(index)*int64(m.Info().FileBlockSize)+10)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+}
+
+func TestVerify(t *testing.T) {
+	store, storage, clean := makeFlist(t, "a", "b", "c")
+	defer clean()
+
+	cache, err := ioutil.TempDir("", "verify-cache-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(cache)
+
+	prefetcher := NewPrefetcher(storage, store, cache, WithPatterns("a", "b"))
+	if ok := assert.NoError(t, prefetcher.Walk(context.Background())); !ok {
+		t.Fatal()
+	}
+
+	corruptBlock(t, prefetcher.fs, "b", 1)
+
+	results := make(map[string]VerifyResult)
+	collect := func(result VerifyResult) {
+		results[result.Path] = result
+	}
+
+	if ok := assert.NoError(t, Verify(store, cache, false, collect)); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, map[string]VerifyResult{
+		"a": {Path: "a", Cached: true},
+		"b": {Path: "b", Cached: true, Corrupt: []int{1}},
+		"c": {Path: "c"},
+	}, results); !ok {
+		t.Error()
+	}
+
+	//repair marks the block as missing, so it's downloaded again
+	if ok := assert.NoError(t, Verify(store, cache, true, collect)); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.False(t, isCached(t, prefetcher.fs, "b")); !ok {
+		t.Error()
+	}
+
+	if ok := assert.NoError(t, prefetcher.Paths(context.Background(), []string{"b"})); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.NoError(t, Verify(store, cache, false, collect)); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, VerifyResult{Path: "b", Cached: true}, results["b"]); !ok {
+		t.Error()
+	}
+}
+
+func TestVerifyOnOpen(t *testing.T) {
+	store, storage, clean := makeFlist(t, "a")
+	defer clean()
+
+	cache, err := ioutil.TempDir("", "verify-cache-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(cache)
+
+	prefetcher := NewPrefetcher(storage, store, cache)
+	if ok := assert.NoError(t, prefetcher.Walk(context.Background())); !ok {
+		t.Fatal()
+	}
+
+	fs := prefetcher.fs
+	fs.SetVerify(true)
+	corruptBlock(t, fs, "a", 2)
+
+	m, _ := fs.getStore().Get("a")
+	file, err := fs.open(m)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	lazy, ok := file.(*lazyFile)
+	if ok := assert.True(t, ok, "corrupt file must be served lazily"); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, []bool{true, true, false}, []bool{lazy.has(0), lazy.has(1), lazy.has(2)}); !ok {
+		t.Error()
+	}
+
+	buf := make([]byte, m.Info().Size)
+	if _, status := file.Read(buf, 0); !assert.True(t, status.Ok()) {
+		t.Fatal()
+	}
+
+	file.Release()
+
+	corrupt := -1
+	err = Verify(store, cache, false, func(result VerifyResult) {
+		if result.Path == "a" {
+			corrupt = len(result.Corrupt)
+		}
+	})
+
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, 0, corrupt); !ok {
+		t.Error()
+	}
+}
@@ -0,0 +1,43 @@
+package router
+
+//Backend is a client to a single destination
+type Backend interface {
+	//Get returns the data stored under key, ErrNotFound if key does not exist
+	Get(key []byte) ([]byte, error)
+	//Set stores data under key
+	Set(key, data []byte) error
+}
+
+//BackendFactory creates a backend client for a destination
+type BackendFactory func(d Destination) (Backend, error)
+
+var (
+	backends = map[string]BackendFactory{
+		"ardb":   newRedisBackend,
+		"zdb":    newRedisBackend,
+		"redis":  newRedisBackend,
+		"zdbs":   newRedisBackend,
+		"rediss": newRedisBackend,
+		"http":   newHTTPBackend,
+		"https":  newHTTPBackend,
+		"dir":    newDirBackend,
+		"s3":     newS3Backend,
+	}
+)
+
+//NewBackend creates a backend client for destination d based on its scheme.
+//The backend is guarded with a circuit breaker, so requests to a destination
+//that is known to be down fail immediately with ErrDestinationDown
+func NewBackend(d Destination) (Backend, error) {
+	factory, ok := backends[d.Scheme]
+	if !ok {
+		return nil, ErrUnknownScheme
+	}
+
+	backend, err := factory(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBreaker(d, backend, healthCheckInterval), nil
+}
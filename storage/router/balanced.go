@@ -0,0 +1,365 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	balanceBackoffBase = 1 * time.Second
+	balanceBackoffMax  = 1 * time.Minute
+	//weight of the last measure in the latency moving average
+	balanceLatencyWeight = 0.3
+)
+
+//Selection defines how a balanced pool orders the destinations that match a hash
+type Selection string
+
+//Selection values
+const (
+	//RoundRobin rotates over the matching destinations
+	RoundRobin = Selection("round-robin")
+	//Latency prefers the matching destinations with the lowest average latency
+	Latency = Selection("latency")
+)
+
+type balancedDestination struct {
+	backend Backend
+
+	latency   time.Duration
+	failures  uint
+	downUntil time.Time
+}
+
+func (d *balancedDestination) healthy(now time.Time) bool {
+	return !now.Before(d.downUntil)
+}
+
+/*
+BalancedPool defines a set of routing rules that spreads the requests over all
+the destinations that match a hash.
+
+Reads are tried on all matching destinations, ordered by the pool Selection.
+A destination that fails is marked unhealthy and is only tried after all the
+healthy ones until its backoff expires, the backoff doubles with each consecutive
+failure. Writes are replicated to Replicas destinations.
+*/
+type BalancedPool struct {
+	Rules     []Rule
+	Selection Selection
+	Replicas  int
+
+	dests   map[Destination]*balancedDestination
+	counter uint64
+
+	m sync.Mutex
+}
+
+//NewBalancedPool initialize a new round robin balanced pool that writes to a single destination
+func NewBalancedPool(rules ...Rule) Pool {
+	return &BalancedPool{
+		Rules:     rules,
+		Selection: RoundRobin,
+		Replicas:  1,
+	}
+}
+
+//BalancedPoolFactory returns a pool factory of balanced pools with the given selection and replicas
+func BalancedPoolFactory(selection Selection, replicas int) PoolFactory {
+	if len(selection) == 0 {
+		selection = RoundRobin
+	}
+
+	if replicas <= 0 {
+		replicas = 1
+	}
+
+	return func(rules ...Rule) Pool {
+		return &BalancedPool{
+			Rules:     rules,
+			Selection: selection,
+			Replicas:  replicas,
+		}
+	}
+}
+
+//In checks if hash is in pool
+func (p *BalancedPool) In(h []byte) bool {
+	for _, rule := range p.Rules {
+		if rule.In(h) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Route returns the preferred destination for hash h
+func (p *BalancedPool) Route(h []byte) Destination {
+	dests := p.Routes(h)
+	if len(dests) == 0 {
+		return nil
+	}
+
+	return dests[0]
+}
+
+//Routes returns all possible destinations for hash h in the order they should be tried
+func (p *BalancedPool) Routes(h []byte) []Destination {
+	var dests []Destination
+	for _, rule := range p.Rules {
+		if rule.In(h) {
+			dests = append(dests, rule.Destination)
+		}
+	}
+
+	return p.order(dests)
+}
+
+func (p *BalancedPool) get(d Destination) *balancedDestination {
+	state, ok := p.dests[d]
+	if !ok {
+		state = &balancedDestination{}
+		if p.dests == nil {
+			p.dests = make(map[Destination]*balancedDestination)
+		}
+		p.dests[d] = state
+	}
+
+	return state
+}
+
+func (p *BalancedPool) order(dests []Destination) []Destination {
+	if len(dests) <= 1 {
+		return dests
+	}
+
+	if p.Selection == Latency {
+		p.m.Lock()
+		latency := make(map[Destination]time.Duration)
+		for _, dest := range dests {
+			latency[dest] = p.get(dest).latency
+		}
+		p.m.Unlock()
+
+		//destinations with no measures yet come first so they get measured
+		sort.SliceStable(dests, func(i, j int) bool {
+			return latency[dests[i]] < latency[dests[j]]
+		})
+	} else {
+		start := int(atomic.AddUint64(&p.counter, 1) % uint64(len(dests)))
+		rotated := make([]Destination, 0, len(dests))
+		rotated = append(rotated, dests[start:]...)
+		dests = append(rotated, dests[:start]...)
+	}
+
+	//unhealthy destinations are moved to the end
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	now := time.Now()
+	var healthy, unhealthy []Destination
+	for _, dest := range dests {
+		if p.get(dest).healthy(now) {
+			healthy = append(healthy, dest)
+		} else {
+			unhealthy = append(unhealthy, dest)
+		}
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+func (p *BalancedPool) getBackend(d Destination) (Backend, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	state := p.get(d)
+	if state.backend != nil {
+		return state.backend, nil
+	}
+
+	backend, err := NewBackend(d)
+	if err != nil {
+		return nil, err
+	}
+
+	state.backend = backend
+	return backend, nil
+}
+
+//success records a successful call to destination d that took latency
+func (p *BalancedPool) success(d Destination, latency time.Duration) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	state := p.get(d)
+	state.failures = 0
+	state.downUntil = time.Time{}
+	if state.latency == 0 {
+		state.latency = latency
+	} else {
+		state.latency = time.Duration(
+			balanceLatencyWeight*float64(latency) + (1-balanceLatencyWeight)*float64(state.latency),
+		)
+	}
+}
+
+//failure marks destination d as unhealthy
+func (p *BalancedPool) failure(d Destination) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	state := p.get(d)
+	backoff := balanceBackoffBase << state.failures
+	if backoff > balanceBackoffMax || backoff <= 0 {
+		backoff = balanceBackoffMax
+	} else {
+		state.failures++
+	}
+
+	state.downUntil = time.Now().Add(backoff)
+}
+
+//Get key from pool
+func (p *BalancedPool) Get(key []byte) ([]byte, error) {
+	dests := p.Routes(key)
+	if len(dests) == 0 {
+		return nil, ErrNotRoutable
+	}
+
+	//lastErr is returned if none of the destinations answered, so an outage
+	//is not reported as a missing key
+	var lastErr error
+	answered := false
+	for _, dest := range dests {
+		backend, err := p.getBackend(dest)
+		if err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		data, err := backend.Get(key)
+		if err == ErrDestinationDown {
+			p.failure(dest)
+			lastErr = err
+			continue
+		} else if err == ErrNotFound {
+			p.success(dest, time.Since(start))
+			answered = true
+			continue
+		} else if err != nil {
+			log.Errorf("destination(%s://%s, %x): %s", dest.Scheme, dest.Host, key, err)
+			p.failure(dest)
+			lastErr = err
+			continue
+		}
+
+		p.success(dest, time.Since(start))
+		return data, nil
+	}
+
+	if !answered && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return nil, ErrNotFound
+}
+
+//Set writes key to Replicas destinations
+func (p *BalancedPool) Set(key, data []byte) error {
+	dests := p.Routes(key)
+	if len(dests) == 0 {
+		return ErrNotRoutable
+	}
+
+	replicas := p.Replicas
+	if replicas <= 0 {
+		replicas = 1
+	}
+
+	if replicas > len(dests) {
+		replicas = len(dests)
+	}
+
+	var errs Errors
+	written := 0
+	for _, dest := range dests {
+		if written == replicas {
+			break
+		}
+
+		backend, err := p.getBackend(dest)
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		if err := backend.Set(key, data); err != nil {
+			log.Errorf("destination(%s://%s, %x): %s", dest.Scheme, dest.Host, key, err)
+			p.failure(dest)
+			errs = errs.Add(errors.Wrapf(err, "destination(%s://%s)", dest.Scheme, dest.Host))
+			continue
+		}
+
+		p.success(dest, time.Since(start))
+		written++
+	}
+
+	if written < replicas {
+		return errs
+	}
+
+	return nil
+}
+
+//Status returns the health of the pool destinations
+func (p *BalancedPool) Status() []DestinationStatus {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	var backends []Backend
+	for _, rule := range p.Rules {
+		if state, ok := p.dests[rule.Destination]; ok && state.backend != nil {
+			backends = append(backends, state.backend)
+		}
+	}
+
+	return backendsStatus(backends)
+}
+
+//Close releases the connections of the pool
+func (p *BalancedPool) Close() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	var backends []Backend
+	for _, state := range p.dests {
+		if state.backend != nil {
+			backends = append(backends, state.backend)
+		}
+	}
+
+	closeBackends(backends)
+	p.dests = nil
+	return nil
+}
+
+func (p *BalancedPool) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("balanced-pool(%s, replicas: %d) {\n", p.Selection, p.Replicas))
+	for _, rule := range p.Rules {
+		buf.WriteString(
+			fmt.Sprintf("%s -> %s://%s\n", rule.Range, rule.Destination.Scheme, rule.Destination.Host),
+		)
+	}
+	buf.WriteString("}")
+
+	return buf.String()
+}
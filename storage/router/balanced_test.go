@@ -0,0 +1,269 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//memBackend is an in memory backend, registered under the mem:// scheme for tests
+type memBackend struct {
+	data   map[string][]byte
+	fail   bool
+	hits   int
+	writes int
+	m      sync.Mutex
+}
+
+func (b *memBackend) Get(key []byte) ([]byte, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.hits++
+	if b.fail {
+		return nil, fmt.Errorf("connection refused")
+	}
+
+	data, ok := b.data[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return data, nil
+}
+
+func (b *memBackend) Set(key, data []byte) error {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.fail {
+		return fmt.Errorf("connection refused")
+	}
+
+	b.writes++
+	b.data[string(key)] = data
+	return nil
+}
+
+var (
+	memBackends  = map[string]*memBackend{}
+	memBackendsM sync.Mutex
+)
+
+func init() {
+	backends["mem"] = func(d Destination) (Backend, error) {
+		memBackendsM.Lock()
+		defer memBackendsM.Unlock()
+
+		return memBackends[d.Host], nil
+	}
+}
+
+//memRules creates a rule per name that matches all hashes and a mem backend for each
+func memRules(names ...string) ([]Rule, []*memBackend) {
+	memBackendsM.Lock()
+	defer memBackendsM.Unlock()
+
+	var rules []Rule
+	var mems []*memBackend
+	for _, name := range names {
+		mem := &memBackend{data: make(map[string][]byte)}
+		memBackends[name] = mem
+		mems = append(mems, mem)
+		rules = append(rules, Rule{exactMatch{}, Destination(&url.URL{Scheme: "mem", Host: name})})
+	}
+
+	return rules, mems
+}
+
+func TestBalancedPoolRoundRobin(t *testing.T) {
+	rules, mems := memRules("rr-1", "rr-2", "rr-3")
+	key := HexToBytes("abcdef")
+	for _, mem := range mems {
+		mem.data[string(key)] = []byte("result value")
+	}
+
+	pool := NewBalancedPool(rules...)
+	for i := 0; i < 30; i++ {
+		data, err := pool.Get(key)
+		if ok := assert.NoError(t, err); !ok {
+			t.Fatal()
+		}
+
+		if ok := assert.Equal(t, "result value", string(data)); !ok {
+			t.Error()
+		}
+	}
+
+	for _, mem := range mems {
+		if ok := assert.Equal(t, 10, mem.hits); !ok {
+			t.Error()
+		}
+	}
+}
+
+func TestBalancedPoolFailover(t *testing.T) {
+	rules, mems := memRules("fo-1", "fo-2")
+	key := HexToBytes("abcdef")
+	for _, mem := range mems {
+		mem.data[string(key)] = []byte("result value")
+	}
+
+	mems[0].fail = true
+
+	pool := NewBalancedPool(rules...)
+	for i := 0; i < 10; i++ {
+		data, err := pool.Get(key)
+		if ok := assert.NoError(t, err); !ok {
+			t.Fatal()
+		}
+
+		if ok := assert.Equal(t, "result value", string(data)); !ok {
+			t.Error()
+		}
+	}
+
+	//the failing destination is tried once, then skipped during its backoff
+	if ok := assert.Equal(t, 1, mems[0].hits); !ok {
+		t.Error()
+	}
+
+	if ok := assert.Equal(t, 10, mems[1].hits); !ok {
+		t.Error()
+	}
+}
+
+func TestBalancedPoolAllFailing(t *testing.T) {
+	rules, mems := memRules("af-1", "af-2")
+	for _, mem := range mems {
+		mem.fail = true
+	}
+
+	pool := NewBalancedPool(rules...)
+	_, err := pool.Get(HexToBytes("abcdef"))
+	if ok := assert.Error(t, err); !ok {
+		t.Fatal()
+	}
+
+	//an outage is not a missing key
+	if ok := assert.NotEqual(t, ErrNotFound, err); !ok {
+		t.Error()
+	}
+}
+
+func TestBalancedPoolLatency(t *testing.T) {
+	rules, mems := memRules("lt-1", "lt-2")
+	key := HexToBytes("abcdef")
+	mems[1].data[string(key)] = []byte("result value")
+
+	pool := BalancedPoolFactory(Latency, 1)(rules...).(*BalancedPool)
+	pool.success(rules[0].Destination, 100)
+	pool.success(rules[1].Destination, 10)
+
+	if ok := assert.Equal(t, rules[1].Destination, pool.Route(key)); !ok {
+		t.Error()
+	}
+}
+
+func TestBalancedPoolReplicas(t *testing.T) {
+	rules, mems := memRules("rp-1", "rp-2", "rp-3")
+	key := HexToBytes("abcdef")
+	mems[0].fail = true
+
+	pool := BalancedPoolFactory(RoundRobin, 2)(rules...)
+	if ok := assert.NoError(t, pool.Set(key, []byte("result value"))); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, 1, mems[1].writes); !ok {
+		t.Error()
+	}
+
+	if ok := assert.Equal(t, 1, mems[2].writes); !ok {
+		t.Error()
+	}
+
+	mems[1].fail = true
+	err := pool.Set(key, []byte("result value"))
+	if ok := assert.Error(t, err); !ok {
+		t.Error()
+	}
+}
+
+func TestConfigPolicies(t *testing.T) {
+	str := `
+pools:
+  hub:
+    00:7f: zdb://destination.1
+    00:ff: zdb://destination.2
+  local:
+    00:ff: zdb://destination.local
+
+policies:
+  hub:
+    type: balanced
+    selection: latency
+    replicas: 2
+
+lookup:
+  - local
+  - hub
+`
+
+	config, err := NewConfig(bytes.NewBufferString(str))
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	router, err := config.Router(nil)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	hub, ok := router.pools["hub"].(*BalancedPool)
+	if ok := assert.True(t, ok); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, Latency, hub.Selection); !ok {
+		t.Error()
+	}
+
+	if ok := assert.Equal(t, 2, hub.Replicas); !ok {
+		t.Error()
+	}
+
+	if _, ok := router.pools["local"].(*ScanPool); !ok {
+		t.Error("expecting a scan pool")
+	}
+}
+
+func TestConfigPoliciesInvalid(t *testing.T) {
+	str := `
+pools:
+  hub:
+    00:ff: zdb://destination
+
+policies:
+  hub:
+    type: unknown
+  missing:
+    type: balanced
+
+lookup:
+  - hub
+`
+
+	_, err := NewConfig(bytes.NewBufferString(str))
+	if ok := assert.Error(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Len(t, err, 2); !ok {
+		t.Error()
+	}
+}
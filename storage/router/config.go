@@ -21,12 +21,63 @@ type PoolFactory func(...Rule) Pool
 //PoolConfig is a map from hash-range to destination
 type PoolConfig map[string]string
 
+//Pool types
+const (
+	//ScanPoolType is the default pool type, see ScanPool
+	ScanPoolType = "scan"
+	//BalancedPoolType spreads the requests over the matching destinations, see BalancedPool
+	BalancedPoolType = "balanced"
+)
+
+//PoolPolicy defines the pool implementation to use for a pool
+type PoolPolicy struct {
+	//Type of the pool, scan (default) or balanced
+	Type string `yaml:"type"`
+	//Selection of the destinations of a balanced pool, round-robin (default) or latency
+	Selection Selection `yaml:"selection"`
+	//Replicas number of destinations a balanced pool writes a block to (default 1)
+	Replicas int `yaml:"replicas"`
+}
+
+//Valid validates the policy
+func (p *PoolPolicy) Valid() error {
+	switch p.Type {
+	case "", ScanPoolType:
+		if len(p.Selection) != 0 || p.Replicas != 0 {
+			return fmt.Errorf("selection and replicas are only supported by balanced pools")
+		}
+	case BalancedPoolType:
+		if p.Selection != "" && p.Selection != RoundRobin && p.Selection != Latency {
+			return fmt.Errorf("unknown selection '%s'", p.Selection)
+		}
+
+		if p.Replicas < 0 {
+			return fmt.Errorf("invalid replicas '%d'", p.Replicas)
+		}
+	default:
+		return fmt.Errorf("unknown pool type '%s'", p.Type)
+	}
+
+	return nil
+}
+
+//Factory returns the pool factory for this policy
+func (p *PoolPolicy) Factory() PoolFactory {
+	if p.Type == BalancedPoolType {
+		return BalancedPoolFactory(p.Selection, p.Replicas)
+	}
+
+	return NewScanPool
+}
+
 //Config defines config file format
 type Config struct {
 	Pools map[string]PoolConfig `yaml:"pools"`
 
 	Lookup []string `yaml:"lookup"`
 	Cache  []string `yaml:"cache"`
+
+	Policies map[string]PoolPolicy `yaml:"policies"`
 }
 
 //Valid validate config structure
@@ -49,6 +100,16 @@ func (c *Config) Valid() error {
 		}
 	}
 
+	for name, policy := range c.Policies {
+		if _, ok := c.Pools[name]; !ok {
+			err = err.Add(fmt.Errorf("no pool with name '%s'", name))
+		}
+
+		if policyErr := policy.Valid(); policyErr != nil {
+			err = err.Add(errors.Wrap(policyErr, name))
+		}
+	}
+
 	for _, pool := range c.Pools {
 		for r, d := range pool {
 			//validate range
@@ -69,12 +130,12 @@ func (c *Config) Valid() error {
 	return nil
 }
 
-//Router returns a router that corresponds to configuration object
+/*
+Router returns a router that corresponds to configuration object. If factory
+is nil, each pool is created according to its policy, pools with no policy
+use the DefaultPoolFactory. Otherwise factory is used for all the pools
+*/
 func (c *Config) Router(factory PoolFactory) (*Router, error) {
-	if factory == nil {
-		factory = DefaultPoolFactory
-	}
-
 	router := Router{
 		pools:  make(map[string]Pool),
 		lookup: c.Lookup,
@@ -101,7 +162,15 @@ func (c *Config) Router(factory PoolFactory) (*Router, error) {
 			rules = append(rules, Rule{hashRange, dest})
 		}
 
-		router.pools[name] = factory(rules...)
+		poolFactory := factory
+		if poolFactory == nil {
+			poolFactory = DefaultPoolFactory
+			if policy, ok := c.Policies[name]; ok {
+				poolFactory = policy.Factory()
+			}
+		}
+
+		router.pools[name] = poolFactory(rules...)
 	}
 
 	return &router, nil
@@ -66,9 +66,9 @@ func dial(network, address string) (net.Conn, error) {
 
 	ip := ips[i]
 	if ip4 := ip.To4(); ip4 != nil {
-		return net.Dial(network, fmt.Sprintf("%s:%s", ip4.String(), parts[1]))
+		return net.Dial(network, net.JoinHostPort(ip4.String(), parts[1]))
 	} else if ip6 := ip.To16(); ip6 != nil {
-		return net.Dial(network, fmt.Sprintf("[%s]:%s", ip6.String(), parts[1]))
+		return net.Dial(network, net.JoinHostPort(ip6.String(), parts[1]))
 	} else {
 		return nil, fmt.Errorf("invalid ip address '%s'", ip.String())
 	}
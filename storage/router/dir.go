@@ -0,0 +1,96 @@
+package router
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+/*
+dirBackend stores blocks as files under a local directory. A block with key
+K is stored at <root>/<hex K[0:2]>/<hex K[2:4]>/<hex K>, the same two level
+layout used by the rofs download cache
+*/
+type dirBackend struct {
+	root string
+}
+
+func newDirBackend(d Destination) (Backend, error) {
+	if len(d.Path) == 0 {
+		return nil, fmt.Errorf("dir destination requires a path")
+	}
+
+	return &dirBackend{root: d.Path}, nil
+}
+
+func (b *dirBackend) path(key []byte) string {
+	hash := fmt.Sprintf("%x", key)
+	base := b.root
+	if len(hash) >= 2 {
+		base = filepath.Join(base, hash[0:2])
+	}
+
+	if len(hash) >= 4 {
+		base = filepath.Join(base, hash[2:4])
+	}
+
+	return filepath.Join(base, hash)
+}
+
+func (b *dirBackend) Get(key []byte) ([]byte, error) {
+	data, err := ioutil.ReadFile(b.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+
+	return data, err
+}
+
+func (b *dirBackend) Check() error {
+	info, err := os.Stat(b.root)
+	if os.IsNotExist(err) {
+		//the directory is created on first write
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("'%s' is not a directory", b.root)
+	}
+
+	return nil
+}
+
+func (b *dirBackend) Set(key, data []byte) error {
+	name := b.path(key)
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	//write to a temporary file first so readers never see a partial block
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,117 @@
+package router
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirPool(t *testing.T) {
+	root, err := ioutil.TempDir("", "dir-pool-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(root)
+
+	dest, err := NewDestination("dir://" + root)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	pool := NewScanPool(Rule{exactMatch{}, dest})
+
+	key := HexToBytes("abcdef")
+	_, err = pool.Get(key)
+	if ok := assert.Equal(t, ErrNotFound, err); !ok {
+		t.Error()
+	}
+
+	if ok := assert.NoError(t, pool.Set(key, []byte("result value"))); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.FileExists(t, filepath.Join(root, "ab", "cd", "abcdef")); !ok {
+		t.Error()
+	}
+
+	data, err := pool.Get(key)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, "result value", string(data)); !ok {
+		t.Error()
+	}
+}
+
+func TestDirDestinationNoPath(t *testing.T) {
+	_, err := NewDestination("dir://")
+	if ok := assert.Error(t, err); !ok {
+		t.Error()
+	}
+}
+
+func TestDirCachePool(t *testing.T) {
+	root, err := ioutil.TempDir("", "dir-pool-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(root)
+
+	key := HexToBytes("abcdef")
+	remote := newBlockServer(map[string][]byte{
+		hex.EncodeToString(key): []byte("result value"),
+	})
+	defer remote.Close()
+
+	config := Config{
+		Pools: map[string]PoolConfig{
+			"local": PoolConfig{
+				"00:FF": "dir://" + root,
+			},
+			"remote": PoolConfig{
+				"00:FF": remote.URL + "/blocks/",
+			},
+		},
+		Lookup: []string{"local", "remote"},
+		Cache:  []string{"local"},
+	}
+
+	if ok := assert.NoError(t, config.Valid()); !ok {
+		t.Fatal()
+	}
+
+	router, err := config.Router(nil)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if _, err := router.Get(key); err != nil {
+		t.Fatal(err)
+	}
+
+	//cache is updated asynchronously
+	name := filepath.Join(root, "ab", "cd", "abcdef")
+	for i := 0; i < 50; i++ {
+		if _, err := os.Stat(name); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, "result value", string(data)); !ok {
+		t.Error()
+	}
+}
@@ -20,6 +20,9 @@ var (
 
 	//ErrUnknownScheme is returned when a not supported scheme is used
 	ErrUnknownScheme = fmt.Errorf("unknown scheme")
+
+	//ErrDestinationDown is returned when a destination is skipped because it is known to be down
+	ErrDestinationDown = fmt.Errorf("destination is down")
 )
 
 //Errors holds many errors at once, suitable for config validation
@@ -0,0 +1,268 @@
+package router
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	healthCheckInterval = 10 * time.Second
+	//breakerThreshold number of consecutive failures before a destination is marked down
+	breakerThreshold = 3
+	//breakerCooldown is the time after which a request is allowed again to a down
+	//destination that has no health checker
+	breakerCooldown = 30 * time.Second
+)
+
+//Checker is implemented by backends that can check the health of their destination
+type Checker interface {
+	Check() error
+}
+
+//State of a destination
+type State int
+
+//State values
+const (
+	//Up destination is serving requests
+	Up State = iota
+	//Down destination is known to be down, requests are not sent to it
+	Down
+)
+
+func (s State) String() string {
+	if s == Down {
+		return "down"
+	}
+
+	return "up"
+}
+
+//DestinationStatus is a snapshot of the health of a destination
+type DestinationStatus struct {
+	//Destination url, without credentials
+	Destination string
+	State       State
+	//Failures is the number of consecutive failures
+	Failures int
+	//LastError is the last error returned by the destination
+	LastError error
+	//Since is the time of the last state change
+	Since time.Time
+}
+
+func (s DestinationStatus) String() string {
+	if s.LastError != nil {
+		return fmt.Sprintf("%s: %s since %s (failures: %d, last error: %s)",
+			s.Destination, s.State, s.Since.Format(time.RFC3339), s.Failures, s.LastError)
+	}
+
+	return fmt.Sprintf("%s: %s since %s", s.Destination, s.State, s.Since.Format(time.RFC3339))
+}
+
+//Statuser is implemented by pools that can report the health of their destinations
+type Statuser interface {
+	Status() []DestinationStatus
+}
+
+/*
+breaker is a circuit breaker around a backend. After breakerThreshold consecutive
+failures the destination is marked down, and requests fail immediately with
+ErrDestinationDown. If the backend implements Checker, a background health check
+marks the destination down after the same number of consecutive failed checks,
+and up again after a successful one, otherwise a single request is let through
+every breakerCooldown to probe the destination.
+*/
+type breaker struct {
+	Backend
+	destination string
+	checker     Checker
+	interval    time.Duration
+
+	state    State
+	failures int
+	lastErr  error
+	since    time.Time
+	probe    time.Time
+
+	stop chan struct{}
+	o    sync.Once
+	m    sync.Mutex
+}
+
+//newBreaker wraps backend with a breaker, the destination health is checked
+//every interval if the backend implements Checker
+func newBreaker(d Destination, backend Backend, interval time.Duration) *breaker {
+	u := url.URL(*d)
+	//credentials and options (namespace, tls) are not part of the label
+	u.User = nil
+	u.RawQuery = ""
+
+	b := &breaker{
+		Backend:     backend,
+		destination: u.String(),
+		interval:    interval,
+		since:       time.Now(),
+		stop:        make(chan struct{}),
+	}
+
+	destinationUp.WithLabelValues(b.destination).Set(1)
+	if checker, ok := backend.(Checker); ok {
+		b.checker = checker
+		go b.check()
+	}
+
+	return b
+}
+
+//Close stops the health check of the destination
+func (b *breaker) Close() error {
+	b.o.Do(func() {
+		close(b.stop)
+	})
+
+	return nil
+}
+
+func (b *breaker) check() {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.stop:
+			return
+		}
+
+		err := b.checker.Check()
+
+		b.m.Lock()
+		if err != nil {
+			b.lastErr = err
+			b.failures++
+			if b.failures >= breakerThreshold {
+				b.set(Down)
+			}
+		} else {
+			b.failures = 0
+			b.set(Up)
+		}
+		b.m.Unlock()
+	}
+}
+
+//set changes the state, must be called with the lock held
+func (b *breaker) set(state State) {
+	if b.state == state {
+		return
+	}
+
+	if state == Down {
+		log.Errorf("destination %s is down: %s", b.destination, b.lastErr)
+		destinationUp.WithLabelValues(b.destination).Set(0)
+	} else {
+		log.Infof("destination %s is up", b.destination)
+		destinationUp.WithLabelValues(b.destination).Set(1)
+	}
+
+	b.state = state
+	b.since = time.Now()
+}
+
+func (b *breaker) allow() bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.state == Up {
+		return true
+	}
+
+	if b.checker != nil {
+		//the health checker will bring the destination up
+		return false
+	}
+
+	if time.Since(b.probe) < breakerCooldown || time.Since(b.since) < breakerCooldown {
+		return false
+	}
+
+	b.probe = time.Now()
+	return true
+}
+
+func (b *breaker) record(err error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if err == nil || err == ErrNotFound {
+		b.failures = 0
+		b.set(Up)
+		return
+	}
+
+	b.lastErr = err
+	b.failures++
+	if b.failures >= breakerThreshold {
+		b.set(Down)
+	}
+}
+
+func (b *breaker) Get(key []byte) ([]byte, error) {
+	if !b.allow() {
+		return nil, ErrDestinationDown
+	}
+
+	data, err := b.Backend.Get(key)
+	b.record(err)
+
+	return data, err
+}
+
+func (b *breaker) Set(key, data []byte) error {
+	if !b.allow() {
+		return ErrDestinationDown
+	}
+
+	err := b.Backend.Set(key, data)
+	b.record(err)
+
+	return err
+}
+
+func (b *breaker) Status() DestinationStatus {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	return DestinationStatus{
+		Destination: b.destination,
+		State:       b.state,
+		Failures:    b.failures,
+		LastError:   b.lastErr,
+		Since:       b.since,
+	}
+}
+
+//closeBackends closes the backends that hold resources (ex: health checks)
+func closeBackends(backends []Backend) {
+	for _, backend := range backends {
+		if closer, ok := backend.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+//backendsStatus returns the status of all backends that are wrapped with a breaker
+func backendsStatus(backends []Backend) []DestinationStatus {
+	var status []DestinationStatus
+	for _, backend := range backends {
+		if b, ok := backend.(*breaker); ok {
+			status = append(status, b.Status())
+		}
+	}
+
+	return status
+}
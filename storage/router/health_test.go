@@ -0,0 +1,197 @@
+package router
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//checkedBackend is a mem backend with a health check
+type checkedBackend struct {
+	*memBackend
+	healthy bool
+	checks  int
+	m       sync.Mutex
+}
+
+func (b *checkedBackend) Check() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.checks++
+	if !b.healthy {
+		return fmt.Errorf("connection refused")
+	}
+
+	return nil
+}
+
+func (b *checkedBackend) setHealthy(healthy bool) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.healthy = healthy
+}
+
+func TestBreakerOpens(t *testing.T) {
+	mem := &memBackend{data: make(map[string][]byte), fail: true}
+	b := newBreaker(Destination(&url.URL{Scheme: "mem", Host: "breaker"}), mem, healthCheckInterval)
+
+	key := HexToBytes("abcdef")
+	for i := 0; i < breakerThreshold; i++ {
+		_, err := b.Get(key)
+		if ok := assert.Error(t, err); !ok {
+			t.Error()
+		}
+	}
+
+	if ok := assert.Equal(t, Down, b.Status().State); !ok {
+		t.Fatal()
+	}
+
+	_, err := b.Get(key)
+	if ok := assert.Equal(t, ErrDestinationDown, err); !ok {
+		t.Error()
+	}
+
+	//backend is not called once the breaker is open
+	if ok := assert.Equal(t, breakerThreshold, mem.hits); !ok {
+		t.Error()
+	}
+}
+
+func TestBreakerHealthCheck(t *testing.T) {
+	backend := &checkedBackend{memBackend: &memBackend{data: make(map[string][]byte)}}
+	b := newBreaker(Destination(&url.URL{Scheme: "mem", Host: "checked", User: url.UserPassword("user", "secret"), RawQuery: "namespace=ns"}), backend, 10*time.Millisecond)
+
+	waitFor := func(state State) bool {
+		for i := 0; i < 100; i++ {
+			if b.Status().State == state {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		return false
+	}
+
+	if ok := assert.True(t, waitFor(Down)); !ok {
+		t.Fatal()
+	}
+
+	//a single failed check is not enough to mark the destination down
+	backend.m.Lock()
+	checks := backend.checks
+	backend.m.Unlock()
+	if ok := assert.True(t, checks >= breakerThreshold); !ok {
+		t.Error()
+	}
+
+	status := b.Status()
+	if ok := assert.Equal(t, "mem://checked", status.Destination); !ok {
+		t.Error()
+	}
+
+	if ok := assert.Error(t, status.LastError); !ok {
+		t.Error()
+	}
+
+	_, err := b.Get(HexToBytes("abcdef"))
+	if ok := assert.Equal(t, ErrDestinationDown, err); !ok {
+		t.Error()
+	}
+
+	backend.setHealthy(true)
+	if ok := assert.True(t, waitFor(Up)); !ok {
+		t.Fatal()
+	}
+
+	_, err = b.Get(HexToBytes("abcdef"))
+	if ok := assert.Equal(t, ErrNotFound, err); !ok {
+		t.Error()
+	}
+}
+
+func TestRouterSkipsDownDestination(t *testing.T) {
+	localRules, local := memRules("down-local")
+	remoteRules, remote := memRules("down-remote")
+
+	key := HexToBytes("abcdef")
+	local[0].fail = true
+	remote[0].data[string(key)] = []byte("result value")
+
+	router := &Router{
+		pools: map[string]Pool{
+			"local":  NewScanPool(localRules...),
+			"remote": NewScanPool(remoteRules...),
+		},
+		lookup: []string{"local", "remote"},
+		cache:  map[string]struct{}{},
+	}
+
+	for i := 0; i < 10; i++ {
+		ret, err := router.Get(key)
+		if ok := assert.NoError(t, err); !ok {
+			t.Fatal()
+		}
+
+		result, _ := ioutil.ReadAll(ret)
+		if ok := assert.Equal(t, "result value", string(result)); !ok {
+			t.Error()
+		}
+	}
+
+	if ok := assert.Equal(t, breakerThreshold, local[0].hits); !ok {
+		t.Error()
+	}
+
+	status := router.Status()
+	if ok := assert.Len(t, status["local"], 1); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, Down, status["local"][0].State); !ok {
+		t.Error()
+	}
+
+	if ok := assert.Equal(t, Up, status["remote"][0].State); !ok {
+		t.Error()
+	}
+}
+
+func TestBreakerClose(t *testing.T) {
+	backend := &checkedBackend{memBackend: &memBackend{data: make(map[string][]byte)}, healthy: true}
+	b := newBreaker(Destination(&url.URL{Scheme: "mem", Host: "closed"}), backend, time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+	if ok := assert.NoError(t, b.Close()); !ok {
+		t.Fatal()
+	}
+
+	//a check can be running while closing
+	time.Sleep(5 * time.Millisecond)
+	backend.m.Lock()
+	checks := backend.checks
+	backend.m.Unlock()
+
+	if ok := assert.NotZero(t, checks); !ok {
+		t.Error()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	backend.m.Lock()
+	defer backend.m.Unlock()
+	if ok := assert.Equal(t, checks, backend.checks); !ok {
+		t.Error()
+	}
+
+	//closing twice is fine
+	if ok := assert.NoError(t, b.Close()); !ok {
+		t.Error()
+	}
+}
@@ -0,0 +1,127 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	httpTimeout         = 1 * time.Minute
+	httpDialTimeout     = 10 * time.Second
+	httpMaxIdleConns    = 100
+	httpMaxIdlePerHost  = 12
+	httpIdleConnTimeout = 1 * time.Minute
+)
+
+var (
+	//httpTransport is shared between all http backends so connections
+	//to the same host are reused across pools
+	httpTransport = &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   httpDialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          httpMaxIdleConns,
+		MaxIdleConnsPerHost:   httpMaxIdlePerHost,
+		IdleConnTimeout:       httpIdleConnTimeout,
+		TLSHandshakeTimeout:   httpDialTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+)
+
+/*
+httpBackend retrieves blocks from an http(s) server. A block with key
+K is available at <base>/<hex K>. Blocks are uploaded with a PUT request
+to the same url.
+*/
+type httpBackend struct {
+	base   string
+	client *http.Client
+}
+
+func newHTTPBackend(d Destination) (Backend, error) {
+	u := *d
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	return &httpBackend{
+		base: u.String(),
+		client: &http.Client{
+			Transport: httpTransport,
+			Timeout:   httpTimeout,
+		},
+	}, nil
+}
+
+func (b *httpBackend) url(key []byte) string {
+	return fmt.Sprintf("%s%x", b.base, key)
+}
+
+func (b *httpBackend) Get(key []byte) ([]byte, error) {
+	response, err := b.client.Get(b.url(key))
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return ioutil.ReadAll(response.Body)
+	case http.StatusNotFound:
+		//drain the body so the connection can be reused
+		io.Copy(ioutil.Discard, response.Body)
+		return nil, ErrNotFound
+	default:
+		io.Copy(ioutil.Discard, response.Body)
+		return nil, fmt.Errorf("invalid response status: %s", response.Status)
+	}
+}
+
+//Check makes sure the server is reachable, any response that is not
+//a server error means the server is up
+func (b *httpBackend) Check() error {
+	response, err := b.client.Head(b.base)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("invalid response status: %s", response.Status)
+	}
+
+	return nil
+}
+
+func (b *httpBackend) Set(key, data []byte) error {
+	request, err := http.NewRequest(http.MethodPut, b.url(key), bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+
+	response, err := b.client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	default:
+		return fmt.Errorf("invalid response status: %s", response.Status)
+	}
+}
@@ -0,0 +1,128 @@
+package router
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type blockServer struct {
+	blocks map[string][]byte
+	m      sync.Mutex
+}
+
+func (s *blockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/blocks/")
+	switch r.Method {
+	case http.MethodGet:
+		data, ok := s.blocks[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	case http.MethodPut:
+		data, _ := ioutil.ReadAll(r.Body)
+		s.blocks[key] = data
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newBlockServer(blocks map[string][]byte) *httptest.Server {
+	return httptest.NewServer(&blockServer{blocks: blocks})
+}
+
+func TestHTTPPool(t *testing.T) {
+	key := HexToBytes("abcdef")
+	server := newBlockServer(map[string][]byte{
+		hex.EncodeToString(key): []byte("result value"),
+	})
+	defer server.Close()
+
+	dest, err := NewDestination(server.URL + "/blocks")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	pool := NewScanPool(Rule{exactMatch{}, dest})
+
+	data, err := pool.Get(key)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, "result value", string(data)); !ok {
+		t.Error()
+	}
+
+	_, err = pool.Get(HexToBytes("123456"))
+	if ok := assert.Equal(t, ErrNotFound, err); !ok {
+		t.Error()
+	}
+
+	if ok := assert.NoError(t, pool.Set(HexToBytes("123456"), []byte("new value"))); !ok {
+		t.Fatal()
+	}
+
+	data, err = pool.Get(HexToBytes("123456"))
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, "new value", string(data)); !ok {
+		t.Error()
+	}
+}
+
+func TestHTTPRouterFallThrough(t *testing.T) {
+	key := HexToBytes("abcdef")
+	local := newBlockServer(map[string][]byte{})
+	defer local.Close()
+
+	remote := newBlockServer(map[string][]byte{
+		hex.EncodeToString(key): []byte("result value"),
+	})
+	defer remote.Close()
+
+	config := Config{
+		Pools: map[string]PoolConfig{
+			"local": PoolConfig{
+				"00:FF": local.URL + "/blocks/",
+			},
+			"remote": PoolConfig{
+				"00:FF": remote.URL + "/blocks/",
+			},
+		},
+		Lookup: []string{"local", "remote"},
+	}
+
+	if ok := assert.NoError(t, config.Valid()); !ok {
+		t.Fatal()
+	}
+
+	router, err := config.Router(nil)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	ret, err := router.Get(key)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	result, _ := ioutil.ReadAll(ret)
+	if ok := assert.Equal(t, "result value", string(result)); !ok {
+		t.Error()
+	}
+}
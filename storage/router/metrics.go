@@ -0,0 +1,55 @@
+package router
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	metricsNamespace = "zerofs"
+	metricsSubsystem = "router"
+)
+
+var (
+	poolHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "pool_hits_total",
+		Help:      "Number of blocks served by a pool",
+	}, []string{"pool"})
+
+	poolFallThroughs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "pool_fallthroughs_total",
+		Help:      "Number of block lookups that fell through a pool to the next one",
+	}, []string{"pool"})
+
+	poolErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "pool_errors_total",
+		Help:      "Number of block lookups that failed on a pool",
+	}, []string{"pool"})
+
+	cacheBacklog = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "cache_backlog",
+		Help:      "Number of blocks waiting to be written to the cache pools",
+	})
+
+	destinationUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "destination_up",
+		Help:      "Health of a destination (1 up, 0 down), see Router.Status for details",
+	}, []string{"destination"})
+
+	cacheWriteErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "cache_write_errors_total",
+		Help:      "Number of blocks that failed to be written to a cache pool",
+	}, []string{"pool"})
+)
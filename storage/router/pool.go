@@ -4,13 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"sync"
-	"time"
-
-	"github.com/garyburd/redigo/redis"
-)
-
-const (
-	blockGetRetries = 3
 )
 
 //Pool defines a pool interface
@@ -27,11 +20,11 @@ This implementation of pool does a sequential scan of the rules. That's not very
 plus it always returns the first match.
 
 More sophisticated implementation of the pool should balance the routing if more than rule matches
-the hash.
+the hash, see BalancedPool.
 */
 type ScanPool struct {
 	Rules []Rule
-	conn  map[Destination]*redis.Pool
+	conn  map[Destination]Backend
 
 	m sync.Mutex
 }
@@ -77,73 +70,26 @@ func (p *ScanPool) Routes(h []byte) []Destination {
 	return dest
 }
 
-func (p *ScanPool) newPool(d Destination) *redis.Pool {
-	return &redis.Pool{
-		Dial: func() (redis.Conn, error) {
-			opts := []redis.DialOption{
-				redis.DialNetDial(dial),
-			}
-
-			if d.User != nil {
-				//assume ardb://password@host.com:port/
-				opts = append(opts, redis.DialPassword(d.User.Username()))
-			}
-
-			return redis.Dial("tcp", d.Host, opts...)
-		},
-		TestOnBorrow: func(c redis.Conn, t time.Time) error {
-			if time.Since(t) > 10*time.Second {
-				//only check connection if more than 10 second of inactivity
-				_, err := c.Do("PING")
-				return err
-			}
-
-			return nil
-		},
-		MaxActive:   12,
-		MaxIdle:     4,
-		IdleTimeout: 1 * time.Minute,
-		Wait:        true,
-	}
-}
-
-func (p *ScanPool) getPool(d Destination) (*redis.Pool, error) {
+func (p *ScanPool) getBackend(d Destination) (Backend, error) {
 	p.m.Lock()
 	defer p.m.Unlock()
 
-	pool, ok := p.conn[d]
+	backend, ok := p.conn[d]
 	if ok {
-		return pool, nil
+		return backend, nil
 	}
 
-	pool = p.newPool(d)
-	if p.conn == nil {
-		p.conn = make(map[Destination]*redis.Pool)
+	backend, err := NewBackend(d)
+	if err != nil {
+		return nil, err
 	}
-	p.conn[d] = pool
 
-	return pool, nil
-}
-
-func (p *ScanPool) get(pool *redis.Pool, key []byte) ([]byte, error) {
-	con := pool.Get()
-	defer con.Close()
-
-	trial := 1
-	var err error
-	var bytes []byte
-	for trial <= blockGetRetries {
-		log.Debugf("try %x: trial %d/%d", key, trial, blockGetRetries)
-		bytes, err = redis.Bytes(con.Do("GET", key))
-		if err == nil || err == redis.ErrNil {
-			log.Debugf("block '%x' has been downloaded successfully", key)
-			return bytes, err
-		}
-		log.Errorf("block '%x' downloading failed with error: %s", key, err)
-		trial++
+	if p.conn == nil {
+		p.conn = make(map[Destination]Backend)
 	}
+	p.conn[d] = backend
 
-	return bytes, err
+	return backend, nil
 }
 
 //Get key from pool
@@ -154,14 +100,17 @@ func (p *ScanPool) Get(key []byte) ([]byte, error) {
 	}
 
 	for _, dest := range dests {
-		pool, err := p.getPool(dest)
+		backend, err := p.getBackend(dest)
 		if err != nil {
 			return nil, err
 		}
 
-		data, err := p.get(pool, key)
-		if err != nil {
-			if err != redis.ErrNil {
+		data, err := backend.Get(key)
+		if err == ErrDestinationDown {
+			log.Debugf("destination(%s://%s, %x): skipped, destination is down", dest.Scheme, dest.Host, key)
+			continue
+		} else if err != nil {
+			if err != ErrNotFound {
 				log.Errorf("destination(%s://%s, %x): %s", dest.Scheme, dest.Host, key, err)
 			}
 
@@ -181,16 +130,42 @@ func (p *ScanPool) Set(key, data []byte) error {
 		return ErrNotRoutable
 	}
 
-	pool, err := p.getPool(dest)
+	backend, err := p.getBackend(dest)
 	if err != nil {
 		return err
 	}
 
-	con := pool.Get()
-	defer con.Close()
+	return backend.Set(key, data)
+}
+
+//Status returns the health of the pool destinations
+func (p *ScanPool) Status() []DestinationStatus {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	var backends []Backend
+	for _, rule := range p.Rules {
+		if backend, ok := p.conn[rule.Destination]; ok {
+			backends = append(backends, backend)
+		}
+	}
+
+	return backendsStatus(backends)
+}
 
-	_, err = con.Do("SET", key, data)
-	return err
+//Close releases the connections of the pool
+func (p *ScanPool) Close() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	var backends []Backend
+	for _, backend := range p.conn {
+		backends = append(backends, backend)
+	}
+
+	closeBackends(backends)
+	p.conn = nil
+	return nil
 }
 
 func (p *ScanPool) String() string {
@@ -52,9 +52,11 @@ func (r *Router) worker() {
 		for name := range r.cache {
 			pool := r.pools[name]
 			if err := pool.Set(chunk.key, chunk.data); err != nil {
+				cacheWriteErrors.WithLabelValues(name).Inc()
 				log.Errorf("failed to update cache pool (%s): %s", name, err)
 			}
 		}
+		cacheBacklog.Dec()
 	}
 }
 
@@ -68,13 +70,17 @@ func (r *Router) get(key []byte) (string, []byte, error) {
 		data, err := pool.Get(key)
 		//only try next entry if entry is not found in this pool, or not routable
 		//otherwise return (nil, or other errors)
-		if err == ErrNotRoutable || err == redis.ErrNil {
+		if err == ErrNotRoutable || err == ErrNotFound || err == redis.ErrNil {
+			poolFallThroughs.WithLabelValues(poolName).Inc()
 			continue
 		} else if err != nil {
+			poolErrors.WithLabelValues(poolName).Inc()
+			poolFallThroughs.WithLabelValues(poolName).Inc()
 			log.Errorf("pool(%s, %x) : %s", poolName, key, err)
 			continue
 		}
 
+		poolHits.WithLabelValues(poolName).Inc()
 		return poolName, data, err
 	}
 
@@ -92,6 +98,7 @@ func (r *Router) updateCache(src string, key []byte, data []byte) {
 		return
 	}
 
+	cacheBacklog.Inc()
 	r.feed <- chunk{key: key, data: data}
 }
 
@@ -107,6 +114,62 @@ func (r *Router) Get(key []byte) (io.ReadCloser, error) {
 	return ioutil.NopCloser(bytes.NewBuffer(data)), nil
 }
 
+/*
+Put stores data under key. The pools are tried in the lookup order, and the
+data is written to the first pool that routes the key and accepts the data.
+If all routing pools fail, an Errors with the failure of each pool is returned
+*/
+func (r *Router) Put(key, data []byte) error {
+	var errs Errors
+	for _, poolName := range r.lookup {
+		pool, ok := r.pools[poolName]
+		if !ok {
+			return ErrPoolNotFound
+		}
+
+		err := pool.Set(key, data)
+		if err == ErrNotRoutable {
+			continue
+		} else if err != nil {
+			log.Errorf("pool(%s, %x) : %s", poolName, key, err)
+			errs = errs.Add(errors.Wrapf(err, "pool(%s)", poolName))
+			continue
+		}
+
+		return nil
+	}
+
+	if errs.HasErrors() {
+		return errs
+	}
+
+	return errors.Wrap(ErrNotRoutable, "no pools matches key")
+}
+
+//Status returns the health of the destinations of all pools that reports it, by pool name.
+//Only destinations that were used at least once are reported
+func (r *Router) Status() map[string][]DestinationStatus {
+	status := make(map[string][]DestinationStatus)
+	for name, pool := range r.pools {
+		if statuser, ok := pool.(Statuser); ok {
+			status[name] = statuser.Status()
+		}
+	}
+
+	return status
+}
+
+//Close releases the connections of all the pools
+func (r *Router) Close() error {
+	for _, pool := range r.pools {
+		if closer, ok := pool.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+
+	return nil
+}
+
 func (r *Router) String() string {
 	var buf bytes.Buffer
 	for name, pool := range r.pools {
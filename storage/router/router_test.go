@@ -1,10 +1,12 @@
 package router
 
 import (
+	"fmt"
 	"io/ioutil"
 	"sync"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -42,6 +44,30 @@ func newTestPool(rules ...Rule) Pool {
 	return &TestPool{}
 }
 
+// newTestRouter creates a router of 2 test pools local and remote, which are
+// looked up in that order
+func newTestRouter(t *testing.T, local, remote string) *Router {
+	config := Config{
+		Pools: map[string]PoolConfig{
+			local: PoolConfig{
+				"00:FF": "ardb://destination.local:1234",
+			},
+			remote: PoolConfig{
+				"00:FF": "ardb://destination.remote:1234",
+			},
+		},
+		Lookup: []string{local, remote},
+	}
+
+	router, err := config.Router(newTestPool)
+
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	return router
+}
+
 func TestRouterGetSuccess(t *testing.T) {
 	config := Config{
 		Pools: map[string]PoolConfig{
@@ -217,3 +243,99 @@ func TestMerget(t *testing.T) {
 	}
 
 }
+
+func TestRouterPutSuccess(t *testing.T) {
+	router := newTestRouter(t, "local", "remote")
+
+	key := HexToBytes("abcdef")
+	value := []byte("result value")
+	local := router.pools["local"].(*TestPool)
+	local.On("Set", key, value).Return(nil)
+	local.wg.Add(1)
+	remote := router.pools["remote"].(*TestPool)
+
+	if ok := assert.NoError(t, router.Put(key, value)); !ok {
+		t.Fatal()
+	}
+
+	if ok := local.AssertCalled(t, "Set", key, value); !ok {
+		t.Error()
+	}
+
+	if ok := remote.AssertNotCalled(t, "Set", key, value); !ok {
+		t.Error()
+	}
+}
+
+func TestRouterPutFallThrough(t *testing.T) {
+	router := newTestRouter(t, "local", "remote")
+
+	key := HexToBytes("abcdef")
+	value := []byte("result value")
+	local := router.pools["local"].(*TestPool)
+	local.On("Set", key, value).Return(fmt.Errorf("connection refused"))
+	local.wg.Add(1)
+	remote := router.pools["remote"].(*TestPool)
+	remote.On("Set", key, value).Return(nil)
+	remote.wg.Add(1)
+
+	if ok := assert.NoError(t, router.Put(key, value)); !ok {
+		t.Fatal()
+	}
+
+	if ok := remote.AssertCalled(t, "Set", key, value); !ok {
+		t.Error()
+	}
+}
+
+func TestRouterPutError(t *testing.T) {
+	router := newTestRouter(t, "local", "remote")
+
+	key := HexToBytes("abcdef")
+	value := []byte("result value")
+	local := router.pools["local"].(*TestPool)
+	local.On("Set", key, value).Return(fmt.Errorf("connection refused"))
+	local.wg.Add(1)
+	remote := router.pools["remote"].(*TestPool)
+	remote.On("Set", key, value).Return(ErrNotRoutable)
+	remote.wg.Add(1)
+
+	err := router.Put(key, value)
+	if ok := assert.Error(t, err); !ok {
+		t.Fatal()
+	}
+
+	errs, ok := err.(Errors)
+	if ok := assert.True(t, ok); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Len(t, errs, 1); !ok {
+		t.Error()
+	}
+}
+
+func TestRouterMetrics(t *testing.T) {
+	router := newTestRouter(t, "metrics-local", "metrics-remote")
+
+	key := HexToBytes("abcdef")
+	router.pools["metrics-local"].(*TestPool).On("Get", key).Return(nil, ErrNotFound)
+	router.pools["metrics-remote"].(*TestPool).On("Get", key).Return([]byte("value"), nil)
+
+	_, err := router.Get(key)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.EqualValues(t, 1, testutil.ToFloat64(poolFallThroughs.WithLabelValues("metrics-local"))); !ok {
+		t.Error()
+	}
+
+	if ok := assert.EqualValues(t, 0, testutil.ToFloat64(poolHits.WithLabelValues("metrics-local"))); !ok {
+		t.Error()
+	}
+
+	if ok := assert.EqualValues(t, 1, testutil.ToFloat64(poolHits.WithLabelValues("metrics-remote"))); !ok {
+		t.Error()
+	}
+}
@@ -1,13 +1,15 @@
 package router
 
 import (
+	"fmt"
 	"net/url"
+	"strings"
 )
 
 var (
 	//SupportedScheme list of supported url scheme
 	SupportedScheme = []string{
-		"ardb", "zdb", "redis",
+		"ardb", "zdb", "redis", "zdbs", "rediss", "http", "https", "dir", "s3",
 	}
 )
 
@@ -41,5 +43,44 @@ func NewDestination(dest string) (Destination, error) {
 		return nil, ErrUnknownScheme
 	}
 
+	if u.Scheme == "dir" && len(u.Path) == 0 {
+		return nil, fmt.Errorf("dir destination requires a path")
+	}
+
+	if err := validTLS(Destination(u)); err != nil {
+		return nil, err
+	}
+
+	if plainScheme(Destination(u)) == "zdb" {
+		if err := validNamespace(u); err != nil {
+			return nil, err
+		}
+	}
+
+	if u.Scheme == "s3" && len(u.Host) == 0 {
+		return nil, fmt.Errorf("s3 destination requires a bucket")
+	}
+
 	return Destination(u), nil
 }
+
+//validNamespace validates the namespace of a zdb destination, the namespace path
+//is normalized so Namespace doesn't need to parse it again
+func validNamespace(u *url.URL) error {
+	namespace := strings.Trim(u.Path, "/")
+	if strings.Contains(namespace, "/") {
+		return fmt.Errorf("invalid zdb namespace '%s'", u.Path)
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok && len(namespace) == 0 {
+			return fmt.Errorf("zdb namespace password requires a namespace")
+		}
+	}
+
+	if len(namespace) != 0 {
+		u.Path = "/" + namespace
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package router
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3DefaultRegion = "us-east-1"
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3TimeFormat    = "20060102T150405Z"
+	s3DateFormat    = "20060102"
+)
+
+/*
+s3Backend stores blocks as objects in an S3 compatible object storage. The
+destination has the form
+
+	s3://[access:secret@]bucket[/prefix][?endpoint=<url>&region=<region>]
+
+A block with key K is stored under the object <prefix>/<hex K>. Credentials
+default to the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables,
+the endpoint to AWS_ENDPOINT_URL and the region to AWS_REGION. If no credentials
+are found, requests are sent anonymously.
+*/
+type s3Backend struct {
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	region   string
+
+	accessKey string
+	secretKey string
+
+	client *http.Client
+}
+
+func env(keys ...string) string {
+	for _, key := range keys {
+		if value := os.Getenv(key); len(value) != 0 {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func newS3Backend(d Destination) (Backend, error) {
+	if len(d.Host) == 0 {
+		return nil, fmt.Errorf("s3 destination requires a bucket")
+	}
+
+	query := (*url.URL)(d).Query()
+	region := query.Get("region")
+	if len(region) == 0 {
+		region = env("AWS_REGION", "AWS_DEFAULT_REGION")
+	}
+
+	if len(region) == 0 {
+		region = s3DefaultRegion
+	}
+
+	endpoint := query.Get("endpoint")
+	if len(endpoint) == 0 {
+		endpoint = env("AWS_ENDPOINT_URL")
+	}
+
+	if len(endpoint) == 0 {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	backend := &s3Backend{
+		endpoint: u,
+		bucket:   d.Host,
+		prefix:   strings.Trim(d.Path, "/"),
+		region:   region,
+		client: &http.Client{
+			Transport: httpTransport,
+			Timeout:   httpTimeout,
+		},
+	}
+
+	if d.User != nil {
+		backend.accessKey = d.User.Username()
+		backend.secretKey, _ = d.User.Password()
+	} else {
+		backend.accessKey = env("AWS_ACCESS_KEY_ID")
+		backend.secretKey = env("AWS_SECRET_ACCESS_KEY")
+	}
+
+	return backend, nil
+}
+
+func (b *s3Backend) url(key []byte) string {
+	u := *b.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + b.bucket
+	if key == nil {
+		return u.String()
+	}
+
+	object := fmt.Sprintf("%x", key)
+	if len(b.prefix) != 0 {
+		object = b.prefix + "/" + object
+	}
+
+	u.Path += "/" + object
+	return u.String()
+}
+
+//do sends a request for object key, or the bucket if key is nil
+func (b *s3Backend) do(method string, key []byte, data []byte) (*http.Response, error) {
+	request, err := http.NewRequest(method, b.url(key), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b.accessKey) != 0 {
+		s3Sign(request, data, b.region, b.accessKey, b.secretKey, time.Now())
+	}
+
+	return b.client.Do(request)
+}
+
+func (b *s3Backend) Get(key []byte) ([]byte, error) {
+	response, err := b.do(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return ioutil.ReadAll(response.Body)
+	case http.StatusNotFound:
+		io.Copy(ioutil.Discard, response.Body)
+		return nil, ErrNotFound
+	default:
+		io.Copy(ioutil.Discard, response.Body)
+		return nil, fmt.Errorf("invalid response status: %s", response.Status)
+	}
+}
+
+//Check makes sure the bucket is reachable
+func (b *s3Backend) Check() error {
+	response, err := b.do(http.MethodHead, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("invalid response status: %s", response.Status)
+	}
+
+	return nil
+}
+
+func (b *s3Backend) Set(key, data []byte) error {
+	response, err := b.do(http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("invalid response status: %s", response.Status)
+	}
+
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+//s3Sign signs the request with aws signature version 4. All headers already set
+//on the request are signed in addition to host, x-amz-date and x-amz-content-sha256
+func s3Sign(r *http.Request, payload []byte, region, accessKey, secretKey string, now time.Time) {
+	now = now.UTC()
+	payloadHash := sha256Hex(payload)
+	r.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	r.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host": r.URL.Host,
+	}
+
+	for name, values := range r.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name)
+		canonicalHeaders.WriteByte(':')
+		canonicalHeaders.WriteString(headers[name])
+		canonicalHeaders.WriteByte('\n')
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	date := now.Format(s3DateFormat)
+	scope := strings.Join([]string{date, region, "s3", "aws4_request"}, "/")
+	toSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(s3TimeFormat),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	r.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, accessKey, scope, signedHeaders, signature,
+	))
+}
@@ -0,0 +1,102 @@
+package router
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+)
+
+var (
+	//tlsSchemes maps the tls enabled schemes to their plain scheme
+	tlsSchemes = map[string]string{
+		"rediss": "redis",
+		"zdbs":   "zdb",
+	}
+)
+
+//plainScheme returns the scheme of the destination without the tls variant
+func plainScheme(d Destination) string {
+	if scheme, ok := tlsSchemes[d.Scheme]; ok {
+		return scheme
+	}
+
+	return d.Scheme
+}
+
+//validTLS validates the tls options of a destination
+func validTLS(d Destination) error {
+	if _, ok := tlsSchemes[d.Scheme]; !ok {
+		return nil
+	}
+
+	query := (*url.URL)(d).Query()
+	if (len(query.Get("cert")) == 0) != (len(query.Get("key")) == 0) {
+		return fmt.Errorf("both cert and key must be set for client authentication")
+	}
+
+	if insecure := query.Get("insecure"); len(insecure) != 0 {
+		if _, err := strconv.ParseBool(insecure); err != nil {
+			return fmt.Errorf("invalid insecure value '%s'", insecure)
+		}
+	}
+
+	return nil
+}
+
+/*
+tlsConfig builds the tls configuration of a destination from the url query,
+supported options are
+	- ca: path to a PEM CA bundle used to verify the server, system CAs are used otherwise
+	- cert, key: path to the PEM client certificate and key
+	- servername: name used to verify the server certificate, defaults to the url host
+	- insecure: skip server certificate verification
+
+Example:
+	zdbs://host:9900/namespace?ca=/etc/zdb/ca.pem&servername=zdb.example.com
+
+It returns nil if the destination does not use tls
+*/
+func tlsConfig(d Destination) (*tls.Config, error) {
+	if _, ok := tlsSchemes[d.Scheme]; !ok {
+		return nil, nil
+	}
+
+	if err := validTLS(d); err != nil {
+		return nil, err
+	}
+
+	query := (*url.URL)(d).Query()
+	config := &tls.Config{
+		ServerName: query.Get("servername"),
+	}
+
+	if insecure := query.Get("insecure"); len(insecure) != 0 {
+		config.InsecureSkipVerify, _ = strconv.ParseBool(insecure)
+	}
+
+	if ca := query.Get("ca"); len(ca) != 0 {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in '%s'", ca)
+		}
+	}
+
+	if cert := query.Get("cert"); len(cert) != 0 {
+		pair, err := tls.LoadX509KeyPair(cert, query.Get("key"))
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{pair}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,127 @@
+package router
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//selfSigned creates a self signed certificate for the given dns name and
+//writes it to dir/ca.pem
+func selfSigned(t *testing.T, dir, name string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := ioutil.WriteFile(filepath.Join(dir, "ca.pem"), certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func TestZdbTLSPool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-")
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	defer os.RemoveAll(dir)
+
+	cert := selfSigned(t, dir, "zdb.test")
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	server := serveFakeZdb(listener, map[string]string{"ns": "secret"})
+	defer server.Close()
+
+	dest, err := NewDestination(fmt.Sprintf(
+		"zdbs://user:secret@%s/ns?ca=%s&servername=zdb.test", server.Addr(), filepath.Join(dir, "ca.pem"),
+	))
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	pool := NewScanPool(Rule{exactMatch{}, dest})
+	key := HexToBytes("abcdef")
+	if ok := assert.NoError(t, pool.Set(key, []byte("result value"))); !ok {
+		t.Fatal()
+	}
+
+	data, err := pool.Get(key)
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	if ok := assert.Equal(t, "result value", string(data)); !ok {
+		t.Error()
+	}
+
+	//server certificate does not match the host name
+	dest, err = NewDestination(fmt.Sprintf("zdbs://%s/ns?ca=%s", server.Addr(), filepath.Join(dir, "ca.pem")))
+	if ok := assert.NoError(t, err); !ok {
+		t.Fatal()
+	}
+
+	pool = NewScanPool(Rule{exactMatch{}, dest})
+	if ok := assert.Error(t, pool.Set(key, []byte("result value"))); !ok {
+		t.Error()
+	}
+}
+
+func TestTLSDestinationInvalid(t *testing.T) {
+	_, err := NewDestination("zdbs://host:9900/ns?cert=/path/to/cert.pem")
+	if ok := assert.Error(t, err); !ok {
+		t.Error()
+	}
+
+	_, err = NewDestination("rediss://host:9900?insecure=maybe")
+	if ok := assert.Error(t, err); !ok {
+		t.Error()
+	}
+}
+
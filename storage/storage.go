@@ -76,3 +76,17 @@ func NewStorage(c io.Reader) (*router.Router, error) {
 type Storage interface {
 	Get(key []byte) (io.ReadCloser, error)
 }
+
+//Writer interface is implemented by storages that accept new blocks
+type Writer interface {
+	Put(key []byte, data []byte) error
+}
+
+//ReadWriter is a storage that can both retrieve and store blocks, the
+//router.Router returned by NewStorage and NewSimpleStorage implements it
+type ReadWriter interface {
+	Storage
+	Writer
+}
+
+var _ ReadWriter = (*router.Router)(nil)